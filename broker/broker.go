@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net"
 	"net/rpc"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
@@ -18,15 +20,85 @@ var (
 	width                 int
 	turn                  int
 	threads               int
+	detectPeriod          int               // longest period of oscillation to look for, 0 to not look
+	skipCycles            bool              // whether to jump to the last turn once the world is found to repeat
+	period                int               // that the world repeats after, 0 until it is found to
+	cycleStart            int               // turn from which the world has repeated
+	regions               *util.RegionStats // population statistics of the world's tiles, nil if not asked for
+	edited                bool              // whether the world has been edited since the last turn
+	running               bool              // whether a game is in progress
+	paused                bool
+	steps                 int  // turns that may still run while paused
+	turnInProgress        bool // whether the servers are working on a turn
 	mutex                 sync.Mutex
+	turnCond              = sync.NewCond(&mutex) // signalled when the pause state changes or a turn ends
 	closeBrokerChan       chan struct{}
-	stopTurnsChan         chan struct{}
+	stopTurnsChan         chan struct{} // closed by stopTurns to cancel the game in progress
+	stopMutex             sync.Mutex
 	turnExecutionFinished sync.WaitGroup
 	servers               []string
 	distClient            *rpc.Client
+	timeout               time.Duration // deadline for each call to a server or the controller
+	security              stubs.Security
+	encodings             []string // world encodings the broker supports
 )
 
-func makeNextStateCall(client *rpc.Client, resultChan chan<- stubs.NextStateResponse, i int) {
+// defaultTimeout is used in place of a -timeout of zero.
+const defaultTimeout = 10 * time.Second
+
+// worldResult holds a (part of a) world along with any error that occurred while computing it.
+type worldResult struct {
+	world   [][]byte
+	elapsed time.Duration // time the server spent computing it
+	err     error
+}
+
+// serverError is returned by nextWorld when a server fails to compute its slice.
+type serverError struct {
+	index int // of the server in servers
+	err   error
+}
+
+func (e *serverError) Error() string {
+	return fmt.Sprintf("server %v: %v", servers[e.index], e.err)
+}
+
+func (e *serverError) Unwrap() error {
+	return e.err
+}
+
+// sliceRows returns the rows of a world of height h that server i computes.
+// This should always divide nicely (since we are hardcoding 4 servers and all given input files are divisible by 4).
+func sliceRows(h, i int) (startY, endY int) {
+	sliceHeight := h / 4
+	return sliceHeight * i, sliceHeight * (i + 1)
+}
+
+// stopTurns cancels the game in progress, if there is one.
+func stopTurns() {
+	stopMutex.Lock()
+	if stopTurnsChan != nil {
+		close(stopTurnsChan)
+		stopTurnsChan = nil
+	}
+	stopMutex.Unlock()
+
+	// wake RunTurns if it is waiting for the game to be resumed
+	mutex.Lock()
+	turnCond.Broadcast()
+	mutex.Unlock()
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func makeNextStateCall(client *rpc.Client, resultChan chan<- worldResult, i int) {
 	mutex.Lock()
 	// store copy of the world to send to server
 	tempWorld := make([][]byte, height)
@@ -41,7 +113,7 @@ func makeNextStateCall(client *rpc.Client, resultChan chan<- stubs.NextStateResp
 	t := threads
 	mutex.Unlock()
 
-	sliceHeight := h / 4 // this should always divide nicely (since we are hardcoding 4 servers and all given input files are divisible by 4)
+	startY, endY := sliceRows(h, i)
 
 	req := stubs.NextStateRequest{
 		World:       tempWorld,
@@ -49,24 +121,47 @@ func makeNextStateCall(client *rpc.Client, resultChan chan<- stubs.NextStateResp
 		WorldWidth:  w,
 		StartX:      0,
 		EndX:        w,
-		StartY:      sliceHeight * i,
-		EndY:        sliceHeight * (i + 1),
+		StartY:      startY,
+		EndY:        endY,
 		Threads:     t,
 	}
 
 	res := new(stubs.NextStateResponse)
-	client.Call(stubs.NextState, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.NextState, req, res, timeout, nil)
+	resultChan <- worldResult{world: res.World, elapsed: res.Elapsed, err: err}
 }
 
-func makeSendWorldStateCall(world [][]byte, cellsFlipped []util.Cell, completedTurns, cellsCount int) {
-	req := stubs.SendWorldStateRequest{
-		CellsFlipped:   cellsFlipped,
-		CompletedTurns: completedTurns,
-		CellsCount:     cellsCount,
-	}
+func makeSendWorldStateCall(controller *rpc.Client, req stubs.SendWorldStateRequest) error {
 	res := new(stubs.SendWorldStateResponse)
-	distClient.Call(stubs.SendWorldState, req, res)
+	return stubs.CallTimeout(controller, stubs.SendWorldState, req, res, timeout, nil)
+}
+
+// reportState sends the world state after a turn to the live viewers, and to controller unless it is nil.
+func reportState(controller *rpc.Client, state stubs.SendWorldStateRequest) error {
+	live.publish(state)
+	if controller == nil {
+		return nil
+	}
+	if err := makeSendWorldStateCall(controller, state); err != nil {
+		return fmt.Errorf("controller: %w", err)
+	}
+	return nil
+}
+
+// reportWorker tells the controller, if there is one, that server i has joined or been lost from the game.
+// A controller that cannot be told is left for the next world state to find.
+func reportWorker(controller *rpc.Client, i int, joined bool, reason string) {
+	if controller == nil {
+		return
+	}
+	mutex.Lock()
+	req := stubs.WorkerChangeRequest{CompletedTurns: turn, Address: servers[i], Joined: joined, Reason: reason}
+	req.StartY, req.EndY = sliceRows(height, i)
+	mutex.Unlock()
+	res := new(stubs.WorkerChangeResponse)
+	if err := stubs.CallTimeout(controller, stubs.SendWorkerChange, req, res, timeout, nil); err != nil {
+		fmt.Println("Error reporting", servers[i], "to the controller:", err)
+	}
 }
 
 // define ReadyToDial that tells the broker it is safe to dial the distributor
@@ -75,54 +170,117 @@ func (g *Broker) ReadyToDial(req stubs.ReadyToDialRequest, res *stubs.ReadyToDia
 	fmt.Println(req.S)
 	distributor := "127.0.0.1:" + req.Port
 	fmt.Println(distributor)
-	client, err := rpc.Dial("tcp", distributor)
+	client, err := security.Dial(distributor, stubs.RoleBroker)
 	if err != nil {
-		log.Fatal("dialing:", err)
+		return fmt.Errorf("dialing the controller: %w", err)
 	}
 	res.S = "broker is connected to controller"
 	distClient = client
+	res.Compression = stubs.ChooseEncoding(req.Compression, encodings)
+	if res.Compression != "" {
+		fmt.Println("Compressing worlds sent to the controller with", res.Compression)
+	}
 	return
 }
 
-func RunTurns(turns int, resultChan chan<- [][]byte) (err error) {
-	defer turnExecutionFinished.Done()
-	turn = 0
+// supportedEncoding returns encoding if the broker supports it, otherwise "" so that the world is sent as is.
+func supportedEncoding(encoding string) string {
+	return stubs.ChooseEncoding([]string{encoding}, encodings)
+}
 
-	clients := make([]*rpc.Client, 4)
+// waitForTurn blocks while the game is paused and reports whether another turn should be run.
+func waitForTurn(turns int, stop <-chan struct{}) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for paused && steps == 0 && !stopped(stop) {
+		turnCond.Wait()
+	}
+	if turn >= turns || stopped(stop) {
+		return false
+	}
+	if paused {
+		steps--
+	}
+	turnInProgress = true
+	return true
+}
+
+// nextWorld asks each server for its slice of the next turn and reassembles them,
+// along with the longest time a server spent computing its slice.
+// It returns nil if stop is closed before all of the slices have arrived.
+func nextWorld(clients []*rpc.Client, stop <-chan struct{}) ([][]byte, time.Duration, error) {
 
+	// list of channels to recieve newe world states
+	// (buffered so that calls abandoned after a cancellation do not leak goroutines)
+	worldResultChannels := make([]chan worldResult, 4)
+
+	// dial servers make rpc calls
 	for i := 0; i < 4; i++ {
-		clients[i], err = rpc.Dial("tcp", servers[i])
-		if err != nil {
-			log.Fatal("dialing:", err)
-		}
+		worldResultChannels[i] = make(chan worldResult, 1)
+		go makeNextStateCall(clients[i], worldResultChannels[i], i)
 	}
 
-TurnsLoop:
-	for ; turn < turns; turn++ {
-		select {
-		case <-stopTurnsChan:
-			break TurnsLoop
-		default:
+	var newWorld [][]byte
+	var compute time.Duration
 
-			// list of channels to recieve newe world states
-			nextStateResultChannels := make([]chan stubs.NextStateResponse, 4)
-
-			// dial servers make rpc calls
-			for i := 0; i < 4; i++ {
-				nextStateResultChannels[i] = make(chan stubs.NextStateResponse)
-				go makeNextStateCall(clients[i], nextStateResultChannels[i], i)
+	// reassemble new world state, abandoning the turn if the game is cancelled
+	for i := 0; i < 4; i++ {
+		select {
+		case result := <-worldResultChannels[i]:
+			if result.err != nil {
+				return nil, 0, &serverError{i, result.err}
+			}
+			newWorld = append(newWorld, result.world...)
+			if result.elapsed > compute {
+				compute = result.elapsed
 			}
+		case <-stop:
+			return nil, 0, nil
+		}
+	}
+	return newWorld, compute, nil
+}
 
-			var newWorld [][]byte
+// RunTurns evolves the world until turns have completed, stop is closed or a call fails,
+// then sends the world and the error (if any) down resultChan.
+// Each turn is reported to controller, unless it is nil.
+func RunTurns(turns int, controller *rpc.Client, stop <-chan struct{}, resultChan chan<- worldResult) {
+	defer turnExecutionFinished.Done()
 
-			// reassemble new world state
-			for i := 0; i < 4; i++ {
-				newWorld = append(newWorld, (<-nextStateResultChannels[i]).World...)
-			}
+	var err error
+	clients := make([]*rpc.Client, 4)
+
+	// a server that cannot be reached ends the game, and is reported as lost after those that joined
+	for i := 0; i < 4; i++ {
+		client, dialErr := security.Dial(servers[i], stubs.RoleBroker)
+		if dialErr != nil {
+			err = &serverError{i, dialErr}
+			break
+		}
+		clients[i] = client
+		defer client.Close()
+		reportWorker(controller, i, true, "")
+	}
 
-			// get world data
-			mutex.Lock()
+	var detector *cycleDetector
+	mutex.Lock()
+	if detectPeriod > 0 {
+		detector = newCycleDetector(detectPeriod, world)
+	}
+	mutex.Unlock()
 
+	lastTurn := time.Now()
+	for err == nil && waitForTurn(turns, stop) {
+		var newWorld [][]byte
+		var timing stubs.TurnTiming
+		start := time.Now()
+		newWorld, timing.Compute, err = nextWorld(clients, stop)
+		timing.Network = time.Since(start) - timing.Compute
+
+		// get world data
+		mutex.Lock()
+		if newWorld != nil {
+			assembly := time.Now()
 			// copy of current world world
 			oldWorld := make([][]byte, height)
 			for i := 0; i < height; i++ {
@@ -130,42 +288,163 @@ TurnsLoop:
 			}
 			copy(oldWorld, world)
 
+			// an edited world may no longer repeat, or may repeat differently, so look for a repeat afresh
+			if edited {
+				edited = false
+				if detectPeriod > 0 {
+					detector = newCycleDetector(detectPeriod, oldWorld)
+					period, cycleStart = 0, 0
+				}
+			}
+
 			copy(world, newWorld)
-			cellsCount := len(calculateAliveCells())
-			cellsFlipped := calculateFlippedCells(oldWorld, world)
-			makeSendWorldStateCall(newWorld, cellsFlipped, turn+1, cellsCount)
-			mutex.Unlock()
+			turn++
+			repeated := false
+			if detector != nil {
+				if p := detector.add(world); p > 0 {
+					period, cycleStart = p, turn-p
+					repeated = true
+					detector = nil
+					fmt.Printf("World repeats every %v turns from turn %v\n", period, cycleStart)
+				}
+			}
+			var flipped []util.Cell
+			if controller != nil || live.watched() || regions != nil {
+				flipped = calculateFlippedCells(oldWorld, world)
+			}
+			if regions != nil {
+				regions.Turn(world, flipped)
+			}
+			if controller != nil || live.watched() {
+				state := stubs.SendWorldStateRequest{
+					CellsFlipped:   flipped,
+					CompletedTurns: turn,
+					CellsCount:     len(calculateAliveCells()),
+				}
+				if repeated {
+					state.Period, state.FirstTurn = period, cycleStart
+				}
+				timing.Assembly = time.Since(assembly)
+				timing.Interval = time.Since(lastTurn)
+				state.Timing = timing
+				err = reportState(controller, state)
+			}
+			// the world is the same after any whole number of periods, so those turns need not be run
+			if repeated && skipCycles && err == nil {
+				if skipped := (turns - turn) / period * period; skipped > 0 {
+					turn += skipped
+					fmt.Println("Skipped to turn", turn)
+					if controller != nil || live.watched() {
+						err = reportState(controller, stubs.SendWorldStateRequest{
+							CompletedTurns: turn,
+							CellsCount:     len(calculateAliveCells()),
+						})
+					}
+				}
+			}
+			lastTurn = time.Now()
+		}
+		turnInProgress = false
+		turnCond.Broadcast()
+		mutex.Unlock()
+
+		if newWorld == nil || err != nil {
+			break
+		}
+	}
+	if err != nil {
+		fmt.Println("Stopping game:", err)
+		var lost *serverError
+		if errors.As(err, &lost) {
+			reportWorker(controller, lost.index, false, lost.err.Error())
 		}
 	}
 	mutex.Lock()
-	resultChan <- world
+	running = false
+	turnCond.Broadcast()
+	resultChan <- worldResult{world: world, err: err}
 	mutex.Unlock()
-	return
 }
 
-type Broker struct{}
-
-func (g *Broker) RunGame(req stubs.RunGameRequest, res *stubs.RunGameResponse) (err error) {
+// startGame sets up the session for a new game and starts running its turns in the background.
+// Each turn is reported to controller, unless it is nil.
+func startGame(req stubs.RunGameRequest, controller *rpc.Client) (<-chan worldResult, error) {
 
 	// set global variables
 	mutex.Lock()
+	if running {
+		mutex.Unlock()
+		return nil, errors.New("a game is already running")
+	}
+	running = true
+	paused = false
+	steps = 0
+	turn = 0
 	world = req.World     // changes after every turn
 	height = req.Height   // should only change after Quit has been called and a new world is passed in to RunGame
 	width = req.Width     // should only change after Quit has been called and a new world is passed in to RunGame
 	threads = req.Threads // should only change after Quit has been called and a new world is passed in to RunGame
+	detectPeriod = req.DetectPeriod
+	skipCycles = req.SkipCycles
+	period = 0
+	cycleStart = 0
+	edited = false
+	regions = nil
+	if req.RegionColumns > 0 && req.RegionRows > 0 {
+		regions = util.NewRegionStats(world, req.RegionColumns, req.RegionRows)
+	}
+	live.restart()
 	mutex.Unlock()
 
-	resultChan := make(chan [][]byte)
+	stopMutex.Lock()
+	stop := make(chan struct{})
+	stopTurnsChan = stop
+	stopMutex.Unlock()
+
+	resultChan := make(chan worldResult)
 	turnExecutionFinished.Add(1)
 
-	go RunTurns(req.Turns, resultChan)
-	res.World = <-resultChan
+	go RunTurns(req.Turns, controller, stop, resultChan)
+	return resultChan, nil
+}
+
+// awaitGame waits for a game started by startGame to stop and fills in its result.
+func awaitGame(resultChan <-chan worldResult, res *stubs.RunGameResponse) error {
+	result := <-resultChan
+	if result.err != nil {
+		return result.err
+	}
+	res.World = result.world
 
 	mutex.Lock()
 	res.CompletedTurns = turn
 	res.AliveCells = calculateAliveCells()
 	mutex.Unlock()
 
+	return nil
+}
+
+type Broker struct{}
+
+func (g *Broker) RunGame(req stubs.RunGameRequest, res *stubs.RunGameResponse) (err error) {
+	req.World, err = stubs.UnpackWorld(req.World, req.Packed)
+	if err != nil {
+		return
+	}
+	if req.Packed.Data != nil {
+		fmt.Println("Received world:", req.Packed)
+	}
+	resultChan, err := startGame(req, distClient)
+	if err != nil {
+		return
+	}
+	err = awaitGame(resultChan, res)
+	// each controller names the encoding it agreed, as controllers that agreed on different ones may share the broker
+	if compression := supportedEncoding(req.Compression); err == nil && compression != "" {
+		res.Packed, err = stubs.PackWorld(res.World, compression)
+		res.World = nil
+		fmt.Println("Sending final world:", res.Packed)
+	}
 	return
 }
 
@@ -177,6 +456,88 @@ func (g *Broker) AliveCellsCount(req stubs.AliveCellsCountRequest, res *stubs.Al
 	return
 }
 
+// Census counts the objects in the current world.
+// The census is taken from a copy of the world, so that turns carry on while it is taken.
+func (g *Broker) Census(req stubs.CensusRequest, res *stubs.CensusResponse) (err error) {
+	mutex.Lock()
+	res.CompletedTurns = turn
+	// turns and EditCells replace rows of the world rather than changing them, so copying the rows is enough
+	current := append([][]byte(nil), world...)
+	mutex.Unlock()
+	res.Census = util.TakeCensus(current)
+	return
+}
+
+// RegionStats returns the population statistics of each tile of the world, if they were asked for
+// when the game was started.
+func (g *Broker) RegionStats(req stubs.RegionStatsRequest, res *stubs.RegionStatsResponse) (err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if regions == nil {
+		return errors.New("region statistics were not asked for when the game was started")
+	}
+	res.CompletedTurns = turn
+	res.Stats = regions.Copy()
+	return
+}
+
+// EditCells toggles cells of a paused game's world, so that the game carries on from the edited world when it is
+// resumed. The cells flipped are reported to live viewers, and count towards the births and deaths of the
+// latest turn in the region statistics.
+func (g *Broker) EditCells(req stubs.EditCellsRequest, res *stubs.EditCellsResponse) (err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !running || !paused {
+		return errors.New("the game must be paused to edit its cells")
+	}
+	// wait for any turn being stepped to finish, which may be the last
+	for turnInProgress {
+		turnCond.Wait()
+	}
+	if !running {
+		return errors.New("the game finished before its cells could be edited")
+	}
+	toggles := make(map[util.Cell]int, len(req.Cells))
+	for _, c := range req.Cells {
+		if c.X < 0 || c.Y < 0 || c.X >= width || c.Y >= height {
+			return fmt.Errorf("cell (%v, %v) is outside the %vx%v world", c.X, c.Y, width, height)
+		}
+		toggles[c]++
+	}
+
+	flipped := make([]util.Cell, 0, len(toggles))
+	copied := make(map[int]bool)
+	for _, c := range req.Cells {
+		if toggles[c]%2 == 0 {
+			continue
+		}
+		toggles[c] = 0 // so that a repeat of the cell is not flipped again
+		// rows are shared with copies of the world that may still be being sent, so edit a copy of the row
+		if !copied[c.Y] {
+			world[c.Y] = append([]byte(nil), world[c.Y]...)
+			copied[c.Y] = true
+		}
+		world[c.Y][c.X] ^= 255
+		flipped = append(flipped, c)
+	}
+	if len(flipped) == 0 {
+		res.CompletedTurns = turn
+		return
+	}
+	edited = true
+	if regions != nil {
+		regions.Turn(world, flipped)
+	}
+	live.publish(stubs.SendWorldStateRequest{
+		CellsFlipped:   flipped,
+		CompletedTurns: turn,
+		CellsCount:     len(calculateAliveCells()),
+	})
+	res.CompletedTurns = turn
+	res.CellsFlipped = flipped
+	return
+}
+
 func (g *Broker) Screenshot(req stubs.ScreenshotRequest, res *stubs.ScreenshotResponse) (err error) {
 	newWorld := make([][]byte, height)
 	for i := 0; i < height; i++ {
@@ -185,12 +546,18 @@ func (g *Broker) Screenshot(req stubs.ScreenshotRequest, res *stubs.ScreenshotRe
 	mutex.Lock()
 	copy(newWorld, world)
 	res.World = newWorld
+	res.CompletedTurns = turn
 	mutex.Unlock()
+	if compression := supportedEncoding(req.Compression); compression != "" {
+		res.Packed, err = stubs.PackWorld(res.World, compression)
+		res.World = nil
+		fmt.Println("Sending screenshot:", res.Packed)
+	}
 	return
 }
 
 func (g *Broker) Quit(req stubs.QuitRequest, res *stubs.QuitResponse) (err error) {
-	stopTurnsChan <- struct{}{} // signal that the client wants to quit
+	stopTurns() // signal that the client wants to quit
 
 	turnExecutionFinished.Wait() // wait for last turn to be completed
 
@@ -200,6 +567,7 @@ func (g *Broker) Quit(req stubs.QuitRequest, res *stubs.QuitResponse) (err error
 	height = 0
 	width = 0
 	world = nil
+	paused = false
 	// ? reset distClient
 	mutex.Unlock()
 
@@ -209,58 +577,98 @@ func (g *Broker) Quit(req stubs.QuitRequest, res *stubs.QuitResponse) (err error
 }
 
 func (g *Broker) CloseBroker(req stubs.CloseBrokerRequest, res *stubs.CloseBrokerResponse) (err error) {
-	close(stopTurnsChan) // close channel (even though that doesn't trigger anything, just cleaning up)
+	stopTurns() // cancel any game that is still running
 
 	// close servers
 	// ! if these requests/responses ever become stateful then will need to make a new req/res pair for each CloseServer call
 	closeServerReq := stubs.CloseServerRequest{}
 	closeServerRes := new(stubs.CloseServerResponse)
+	// the broker shuts down even if a server could not be closed, and the caller is told which
 	err = makeCloseServerCall(closeServerReq, closeServerRes)
-	if err != nil {
-		log.Fatal("Error closing the server:", err)
-	}
 
 	close(closeBrokerChan) // signal that we want to close the Broker down
 	return
 }
 
+// makeCloseServerCall closes every server it can, and returns the error from the first that it could not.
 func makeCloseServerCall(req stubs.CloseServerRequest, res *stubs.CloseServerResponse) (err error) {
-
-	// Create rpc clients to connect to the servers
-	clients := make([]*rpc.Client, 4)
-
-	for i := 0; i < 4; i++ {
-		clients[i], err = rpc.Dial("tcp", servers[i]) // dial server
-		if err != nil {
-			log.Fatal("dialing:", err)
+	for i := range servers {
+		client, dialErr := security.Dial(servers[i], stubs.RoleAdmin) // dial server (closing it needs the admin role)
+		if dialErr != nil {
+			if err == nil {
+				err = fmt.Errorf("closing server %v: %w", servers[i], dialErr)
+			}
+			continue
 		}
-		err = clients[i].Call(stubs.CloseServer, req, res) // close server
-		if err != nil {
-			log.Fatal("Error calling CloseServer on the server:", err)
+		callErr := stubs.CallTimeout(client, stubs.CloseServer, req, res, timeout, nil) // close server
+		client.Close()
+		if callErr != nil && err == nil {
+			err = fmt.Errorf("closing server %v: %w", servers[i], callErr)
 		}
 	}
 	return
 }
 
+// Pause stops the game before its next turn and returns once any turn in progress has finished.
 func (g *Broker) Pause(req stubs.PauseRequest, res *stubs.PauseResponse) (err error) {
 	mutex.Lock()
+	defer mutex.Unlock()
+	paused = true
+	steps = 0
+	for turnInProgress {
+		turnCond.Wait()
+	}
 	res.Turn = turn
 	return
 }
 
-func (g *Broker) Restart(req stubs.PauseRequest, res *stubs.PauseResponse) (err error) {
+func (g *Broker) Restart(req stubs.RestartRequest, res *stubs.RestartResponse) (err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	paused = false
+	turnCond.Broadcast()
+	res.Turn = turn
+	return
+}
+
+// Step runs a single turn of a paused game and returns once it has finished.
+func (g *Broker) Step(req stubs.StepRequest, res *stubs.StepResponse) (err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !running || !paused {
+		return errors.New("the game must be paused to step")
+	}
+	target := turn + steps + 1
+	steps++
+	turnCond.Broadcast()
+	for running && turn < target {
+		turnCond.Wait()
+	}
 	res.Turn = turn
-	mutex.Unlock()
 	return
 }
 
 func main() {
-	pAddr := "8030"
+	var pAddr, httpAddr, encodingList string
+	flag.StringVar(&pAddr, "port", "8030", "set the port that the broker will listen on")
+	flag.StringVar(&httpAddr, "http", "", "set the address of the HTTP/JSON API, e.g. :8080 (disabled if empty)")
+	flag.DurationVar(&timeout, "timeout", defaultTimeout, "set the deadline for each call to a server or the controller (negative disables it, 0 for 10s)")
+	flag.StringVar(&encodingList, "compression", stubs.Flate, "set the comma separated world encodings offered to controllers (none if empty)")
+	security.RegisterFlags()
+	flag.Parse()
+	// as for the controller's -timeout, zero is the default rather than no deadline
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if encodingList != "" {
+		encodings = strings.Split(encodingList, ",")
+	}
+
 	// Registering our service
 	rpc.Register(&Broker{})
 
 	// Create a network listener
-	listener, err := net.Listen("tcp", ":"+pAddr)
+	listener, err := security.Listen(":" + pAddr)
 	if err != nil {
 		fmt.Println("Error starting Broker:", err)
 		return
@@ -272,20 +680,23 @@ func main() {
 		servers[i] = "127.0.0.1:" + strconv.Itoa(8050+i)
 	}
 
-	// Initialise closeBrokerChan and stopTurnsChan
+	// Initialise closeBrokerChan
 	closeBrokerChan = make(chan struct{})
-	stopTurnsChan = make(chan struct{})
 
-	// Goroutine to accept connections using rpc.Accept
+	// Goroutine to accept connections using security.Accept
 	go func() {
 		defer listener.Close()
 
 		fmt.Println("Broker listening on", listener.Addr())
 
 		// Accept connections and serve them
-		rpc.Accept(listener)
+		security.Accept(listener)
 	}()
 
+	if httpAddr != "" {
+		go serveHTTP(httpAddr)
+	}
+
 	// Block until a close signal is received
 	<-closeBrokerChan
 	fmt.Println("Broker shutdown complete")
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestEditCells checks that only a paused game can be edited, and that cells toggled an even number of times
+// are left as they were.
+func TestEditCells(t *testing.T) {
+	setTestWorld(t)
+	mutex.Lock()
+	running = true
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		running, edited = false, false
+		mutex.Unlock()
+	})
+
+	broker := new(Broker)
+	req := stubs.EditCellsRequest{Cells: []util.Cell{{X: 1, Y: 2}, {X: 0, Y: 0}, {X: 3, Y: 1}, {X: 0, Y: 0}}}
+	if err := broker.EditCells(req, new(stubs.EditCellsResponse)); err == nil {
+		t.Fatal("expected a running game to refuse edits")
+	}
+
+	mutex.Lock()
+	paused = true
+	mutex.Unlock()
+	if err := broker.EditCells(stubs.EditCellsRequest{Cells: []util.Cell{{X: 4, Y: 0}}}, new(stubs.EditCellsResponse)); err == nil {
+		t.Fatal("expected a cell outside the world to be refused")
+	}
+	res := new(stubs.EditCellsResponse)
+	if err := broker.EditCells(req, res); err != nil {
+		t.Fatal(err)
+	}
+	expected := []util.Cell{{X: 1, Y: 2}, {X: 3, Y: 1}}
+	if !reflect.DeepEqual(res.CellsFlipped, expected) {
+		t.Errorf("expected %v to be flipped, got %v", expected, res.CellsFlipped)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if alive := calculateAliveCells(); !reflect.DeepEqual(alive, []util.Cell{{X: 3, Y: 1}}) {
+		t.Errorf("expected only (3, 1) to be alive, got %v", alive)
+	}
+	if !edited {
+		t.Error("expected the world to be marked as edited")
+	}
+}
+
+// TestScreenshotCompression checks that each screenshot is packed with the encoding named in its request,
+// so that controllers that agreed on different encodings are each sent worlds they can read.
+func TestScreenshotCompression(t *testing.T) {
+	setTestWorld(t)
+	encodings = []string{stubs.Flate}
+	t.Cleanup(func() { encodings = nil })
+
+	plain := new(stubs.ScreenshotResponse)
+	if err := new(Broker).Screenshot(stubs.ScreenshotRequest{}, plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain.World == nil || plain.Packed.Data != nil {
+		t.Errorf("expected a world sent as is, got %v", plain.Packed)
+	}
+
+	packed := new(stubs.ScreenshotResponse)
+	if err := new(Broker).Screenshot(stubs.ScreenshotRequest{Compression: stubs.Flate}, packed); err != nil {
+		t.Fatal(err)
+	}
+	world, err := stubs.UnpackWorld(packed.World, packed.Packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packed.World != nil || !reflect.DeepEqual(world, plain.World) {
+		t.Errorf("expected the same world packed, got %v", packed.Packed)
+	}
+}
+
+// fakeController stands in for the controller, keeping the worker changes it is sent.
+type fakeController struct {
+	mutex   sync.Mutex
+	changes []stubs.WorkerChangeRequest
+}
+
+func (c *fakeController) SendWorkerChange(req stubs.WorkerChangeRequest, res *stubs.WorkerChangeResponse) (err error) {
+	c.mutex.Lock()
+	c.changes = append(c.changes, req)
+	c.mutex.Unlock()
+	return
+}
+
+var (
+	controller             = new(fakeController)
+	registerFakeController sync.Once
+)
+
+// TestUnreachableServer checks that a server that cannot be dialled ends the game with an error rather than
+// the broker, and is reported as lost after the servers that joined.
+func TestUnreachableServer(t *testing.T) {
+	startFakeServers(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers[2] = listener.Addr().String()
+	listener.Close()
+
+	registerFakeController.Do(func() {
+		if err := rpc.RegisterName("Controller", controller); err != nil {
+			t.Fatal(err)
+		}
+	})
+	controller.mutex.Lock()
+	controller.changes = nil
+	controller.mutex.Unlock()
+	controllerListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controllerListener.Close()
+	go security.Accept(controllerListener)
+	client, err := security.Dial(controllerListener.Addr().String(), stubs.RoleBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	world := [][]byte{make([]byte, 4), make([]byte, 4), make([]byte, 4), make([]byte, 4)}
+	resultChan, err := startGame(stubs.RunGameRequest{Turns: 10, Height: 4, Width: 4, Threads: 1, World: world}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = awaitGame(resultChan, new(stubs.RunGameResponse))
+	var lost *serverError
+	if !errors.As(err, &lost) || lost.index != 2 {
+		t.Fatalf("expected the game to fail on server 2, got %v", err)
+	}
+	new(Broker).Quit(stubs.QuitRequest{}, new(stubs.QuitResponse))
+
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	if len(controller.changes) != 3 {
+		t.Fatalf("expected two joins and a loss, got %+v", controller.changes)
+	}
+	for i, change := range controller.changes[:2] {
+		if !change.Joined || change.Address != servers[i] {
+			t.Errorf("expected %v to join, got %+v", servers[i], change)
+		}
+	}
+	if change := controller.changes[2]; change.Joined || change.Address != servers[2] || change.Reason == "" {
+		t.Errorf("expected %v to be lost with a reason, got %+v", servers[2], change)
+	}
+}
+
+// TestCensus checks that the census taken from a copy of the world is of the current world.
+func TestCensus(t *testing.T) {
+	setTestWorld(t)
+	res := new(stubs.CensusResponse)
+	if err := new(Broker).Census(stubs.CensusRequest{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (util.Census{"other (1 cell)": 1}); !reflect.DeepEqual(res.Census, expected) {
+		t.Errorf("expected %v, got %v", expected, res.Census)
+	}
+}
+
+// TestUnreachableController checks that a controller that cannot be dialled back is reported to it as an error.
+func TestUnreachableController(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	if err := new(Broker).ReadyToDial(stubs.ReadyToDialRequest{Port: port}, new(stubs.ReadyToDialResponse)); err == nil {
+		t.Error("expected an error dialling a controller that is not listening")
+	}
+}
+
+// TestCloseUnreachableServers checks that servers that cannot be closed are reported as an error, and the others
+// are still closed.
+func TestCloseUnreachableServers(t *testing.T) {
+	startFakeServers(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers[1] = listener.Addr().String()
+	listener.Close()
+
+	testServer.mutex.Lock()
+	testServer.closed = 0
+	testServer.mutex.Unlock()
+	err = makeCloseServerCall(stubs.CloseServerRequest{}, new(stubs.CloseServerResponse))
+	if err == nil || !strings.Contains(err.Error(), servers[1]) {
+		t.Errorf("expected an error closing %v, got %v", servers[1], err)
+	}
+	testServer.mutex.Lock()
+	defer testServer.mutex.Unlock()
+	if testServer.closed != len(servers)-1 {
+		t.Errorf("expected %v servers to be closed, got %v", len(servers)-1, testServer.closed)
+	}
+}
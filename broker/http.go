@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// The HTTP API drives the same session as the Broker rpc methods, for clients that cannot speak gob:
+//
+//	POST /game?turns=N&threads=T  start a game from the PGM or PBM in the request body,
+//	                              adding &period=P to look for cycles up to period P and &skip=1 to skip them
+//	GET  /game                    report the turn, alive cell count and state of the game, and any cycle found
+//	POST /game/pause              pause before the next turn
+//	POST /game/resume             resume a paused game
+//	POST /game/step               run a single turn of a paused game
+//	GET  /game/screenshot         download the current world, as ?format=pgm (default) or png
+//	POST /game/quit               stop the game
+//	GET  /live/                   watch the game in a browser (see live.go)
+//
+// If tokens are configured they are read from an "Authorization: Bearer <token>" header,
+// or from a token query parameter since browsers cannot set headers on WebSocket requests.
+// A missing or unknown token, or any other authorization scheme, is refused with 401,
+// and a token whose role is too low with 403.
+
+var (
+	httpMutex   sync.Mutex
+	httpGameErr error // why the last game started over HTTP stopped early, if it did
+)
+
+// gameStatus is the JSON reply to GET /game.
+type gameStatus struct {
+	Running        bool   `json:"running"`
+	Paused         bool   `json:"paused"`
+	CompletedTurns int    `json:"completedTurns"`
+	AliveCells     int    `json:"aliveCells"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	Cycle          *cycle `json:"cycle,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// cycle describes how the world repeats, once it has been found to.
+type cycle struct {
+	Period    int `json:"period"`
+	FirstTurn int `json:"firstTurn"`
+}
+
+// turnReply is the JSON reply to requests that change the state of the game.
+type turnReply struct {
+	CompletedTurns int `json:"completedTurns"`
+}
+
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+// serveHTTP serves the HTTP API on addr until the broker shuts down.
+func serveHTTP(addr string) {
+	listener, err := security.Listen(addr)
+	if err != nil {
+		fmt.Println("Error starting HTTP API:", err)
+		return
+	}
+	fmt.Println("HTTP API listening on", listener.Addr())
+	http.Serve(listener, newHTTPHandler())
+}
+
+func newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/game", handle(stubs.RunGame, handleGame))
+	mux.HandleFunc("/game/pause", handle(stubs.Pause, post(handlePause)))
+	mux.HandleFunc("/game/resume", handle(stubs.Restart, post(handleResume)))
+	mux.HandleFunc("/game/step", handle(stubs.Step, post(handleStep)))
+	mux.HandleFunc("/game/screenshot", handle(stubs.Screenshot, handleScreenshot))
+	mux.HandleFunc("/game/quit", handle(stubs.Quit, post(handleQuit)))
+	mux.HandleFunc("/live/", handle(stubs.AliveCellsCount, handleLivePage))
+	mux.HandleFunc("/live/ws", handle(stubs.AliveCellsCount, handleLiveSocket))
+	return mux
+}
+
+// handle checks the request's token against the rpc method that the handler stands in for.
+func handle(serviceMethod string, handler func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := requestToken(r)
+		if err == nil {
+			err = security.Authorize(token, serviceMethod)
+		}
+		if err != nil {
+			status := http.StatusForbidden
+			if errors.Is(err, stubs.ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				status = http.StatusUnauthorized
+			}
+			writeJSON(w, status, errorReply{err.Error()})
+			return
+		}
+		if err := handler(w, r); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorReply{err.Error()})
+		}
+	}
+}
+
+// requestToken returns the token from the Authorization header, which must use the Bearer scheme,
+// or from the token query parameter if there is no header.
+func requestToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return r.URL.Query().Get("token"), nil
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", fmt.Errorf("authorization scheme is not Bearer: %w", stubs.ErrUnauthenticated)
+	}
+	return strings.TrimSpace(parts[1]), nil
+}
+
+func post(handler func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return fmt.Errorf("%v %v is not supported", r.Method, r.URL.Path)
+		}
+		return handler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func handleGame(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return handleStatus(w, r)
+	case http.MethodPost:
+		return handleStart(w, r)
+	}
+	return fmt.Errorf("%v %v is not supported", r.Method, r.URL.Path)
+}
+
+func handleStart(w http.ResponseWriter, r *http.Request) error {
+	turns, err := queryInt(r, "turns", 10000000000)
+	if err != nil {
+		return err
+	}
+	threads, err := queryInt(r, "threads", 8)
+	if err != nil {
+		return err
+	}
+	detectPeriod, err := queryInt(r, "period", 0)
+	if err != nil {
+		return err
+	}
+	skip, err := queryInt(r, "skip", 0)
+	if err != nil {
+		return err
+	}
+	world, err := util.ReadNetpbm(r.Body)
+	if err != nil {
+		return err
+	}
+
+	req := stubs.RunGameRequest{
+		Turns:   turns,
+		Height:  len(world),
+		Width:   len(world[0]),
+		Threads: threads,
+		World:   world,
+
+		DetectPeriod: detectPeriod,
+		SkipCycles:   skip != 0,
+	}
+	// nobody is listening for the world state after each turn, so no controller is passed in
+	resultChan, err := startGame(req, nil)
+	if err != nil {
+		writeJSON(w, http.StatusConflict, errorReply{err.Error()})
+		return nil
+	}
+	httpMutex.Lock()
+	httpGameErr = nil
+	httpMutex.Unlock()
+	go func() {
+		err := awaitGame(resultChan, new(stubs.RunGameResponse))
+		httpMutex.Lock()
+		httpGameErr = err
+		httpMutex.Unlock()
+	}()
+
+	writeJSON(w, http.StatusAccepted, turnReply{0})
+	return nil
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) error {
+	res := new(stubs.AliveCellsCountResponse)
+	if err := new(Broker).AliveCellsCount(stubs.AliveCellsCountRequest{}, res); err != nil {
+		return err
+	}
+	status := gameStatus{
+		CompletedTurns: res.CompletedTurns,
+		AliveCells:     res.CellsCount,
+	}
+	mutex.Lock()
+	status.Running = running
+	status.Paused = paused
+	status.Width = width
+	status.Height = height
+	if period > 0 {
+		status.Cycle = &cycle{period, cycleStart}
+	}
+	mutex.Unlock()
+	httpMutex.Lock()
+	if httpGameErr != nil {
+		status.Error = httpGameErr.Error()
+	}
+	httpMutex.Unlock()
+	writeJSON(w, http.StatusOK, status)
+	return nil
+}
+
+func handlePause(w http.ResponseWriter, r *http.Request) error {
+	res := new(stubs.PauseResponse)
+	if err := new(Broker).Pause(stubs.PauseRequest{}, res); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, turnReply{res.Turn})
+	return nil
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) error {
+	res := new(stubs.RestartResponse)
+	if err := new(Broker).Restart(stubs.RestartRequest{}, res); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, turnReply{res.Turn})
+	return nil
+}
+
+func handleStep(w http.ResponseWriter, r *http.Request) error {
+	res := new(stubs.StepResponse)
+	if err := new(Broker).Step(stubs.StepRequest{}, res); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, turnReply{res.Turn})
+	return nil
+}
+
+func handleQuit(w http.ResponseWriter, r *http.Request) error {
+	mutex.Lock()
+	turnsDone := turn
+	mutex.Unlock()
+	if err := new(Broker).Quit(stubs.QuitRequest{}, new(stubs.QuitResponse)); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, turnReply{turnsDone})
+	return nil
+}
+
+func handleScreenshot(w http.ResponseWriter, r *http.Request) error {
+	res := new(stubs.ScreenshotResponse)
+	if err := new(Broker).Screenshot(stubs.ScreenshotRequest{}, res); err != nil {
+		return err
+	}
+	shot, err := stubs.UnpackWorld(res.World, res.Packed)
+	if err != nil {
+		return err
+	}
+	if len(shot) == 0 {
+		return errors.New("there is no world to take a screenshot of")
+	}
+
+	h := len(shot)
+	wd := len(shot[0])
+	switch r.URL.Query().Get("format") {
+	case "", "pgm":
+		w.Header().Set("Content-Type", "image/x-portable-graymap")
+		fmt.Fprintf(w, "P5\n%v %v\n255\n", wd, h)
+		for _, row := range shot {
+			w.Write(row)
+		}
+	case "png":
+		data, err := encodePng(shot)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	default:
+		return fmt.Errorf("unknown screenshot format %q", r.URL.Query().Get("format"))
+	}
+	return nil
+}
+
+func encodePng(world [][]byte) ([]byte, error) {
+	img := image.NewGray(image.Rect(0, 0, len(world[0]), len(world)))
+	for y, row := range world {
+		copy(img.Pix[y*img.Stride:], row)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %v %q", key, s)
+	}
+	return n, nil
+}
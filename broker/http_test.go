@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"image/png"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"sync"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// fakeServer stands in for a server, returning its slice of the world unchanged so that every turn is the same.
+type fakeServer struct {
+	mutex  sync.Mutex
+	closed int // calls to CloseServer
+}
+
+func (s *fakeServer) ReturnNextState(req stubs.NextStateRequest, res *stubs.NextStateResponse) (err error) {
+	res.World = req.World[req.StartY:req.EndY]
+	return
+}
+
+func (s *fakeServer) CloseServer(req stubs.CloseServerRequest, res *stubs.CloseServerResponse) (err error) {
+	s.mutex.Lock()
+	s.closed++
+	s.mutex.Unlock()
+	return
+}
+
+var (
+	testServer         = new(fakeServer)
+	registerFakeServer sync.Once
+)
+
+// startFakeServers points the broker at four fake servers for the length of the test.
+func startFakeServers(t *testing.T) {
+	registerFakeServer.Do(func() {
+		if err := rpc.RegisterName("Server", testServer); err != nil {
+			t.Fatal(err)
+		}
+	})
+	servers = make([]string, 4)
+	for i := range servers {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go security.Accept(listener)
+		servers[i] = listener.Addr().String()
+	}
+	t.Cleanup(func() { servers = nil })
+}
+
+// call makes a request to the HTTP API and decodes a JSON reply into v, unless v is nil.
+func call(t *testing.T, server *httptest.Server, method, path, token, body string, v interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatalf("%v %v: %v", method, path, err)
+		}
+	}
+	return res
+}
+
+// TestHTTPGame starts a game over the HTTP API, then pauses, steps, screenshots, resumes and quits it.
+func TestHTTPGame(t *testing.T) {
+	startFakeServers(t)
+	server := httptest.NewServer(newHTTPHandler())
+	defer server.Close()
+
+	pgm := "P5\n4 4\n255\n" + string([]byte{
+		0, 0, 0, 0,
+		0, 255, 255, 0,
+		0, 255, 255, 0,
+		0, 0, 0, 0,
+	})
+	var reply turnReply
+	if res := call(t, server, http.MethodPost, "/game?turns=100000000&threads=1", "", pgm, &reply); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the game to start with 202, got %v", res.Status)
+	}
+	if res := call(t, server, http.MethodPost, "/game", "", pgm, nil); res.StatusCode != http.StatusConflict {
+		t.Errorf("expected a second game to be refused with 409, got %v", res.Status)
+	}
+
+	if res := call(t, server, http.MethodPost, "/game/pause", "", "", &reply); res.StatusCode != http.StatusOK {
+		t.Fatalf("pause: %v", res.Status)
+	}
+	paused := reply.CompletedTurns
+	var status gameStatus
+	call(t, server, http.MethodGet, "/game", "", "", &status)
+	if !status.Running || !status.Paused || status.CompletedTurns != paused || status.AliveCells != 4 || status.Width != 4 || status.Height != 4 {
+		t.Errorf("expected a paused 4x4 game at turn %v with 4 alive cells, got %+v", paused, status)
+	}
+
+	if res := call(t, server, http.MethodPost, "/game/step", "", "", &reply); res.StatusCode != http.StatusOK || reply.CompletedTurns != paused+1 {
+		t.Errorf("expected a step to turn %v, got %v at turn %v", paused+1, res.Status, reply.CompletedTurns)
+	}
+	if res := call(t, server, http.MethodGet, "/game/step", "", "", nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected GET /game/step to be refused with 400, got %v", res.Status)
+	}
+
+	res, err := http.Get(server.URL + "/game/screenshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shot, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.Header.Get("Content-Type") != "image/x-portable-graymap" || string(shot) != pgm {
+		t.Errorf("expected the world as a pgm, got %q", shot)
+	}
+	res, err = http.Get(server.URL + "/game/screenshot?format=png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(res.Body)
+	res.Body.Close()
+	if err != nil || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 4 {
+		t.Errorf("expected a 4x4 png, got %v", err)
+	}
+
+	if res := call(t, server, http.MethodPost, "/game/resume", "", "", &reply); res.StatusCode != http.StatusOK {
+		t.Errorf("resume: %v", res.Status)
+	}
+	if res := call(t, server, http.MethodPost, "/game/quit", "", "", &reply); res.StatusCode != http.StatusOK || reply.CompletedTurns <= paused {
+		t.Errorf("expected quit after turn %v, got %v at turn %v", paused, res.Status, reply.CompletedTurns)
+	}
+	status = gameStatus{}
+	call(t, server, http.MethodGet, "/game", "", "", &status)
+	if status.Running || status.Width != 0 {
+		t.Errorf("expected no game after quitting, got %+v", status)
+	}
+}
+
+// TestHTTPAuthorization checks that a missing or unknown token is refused with 401,
+// and a token whose role is too low for the method with 403.
+func TestHTTPAuthorization(t *testing.T) {
+	security = stubs.Security{ControllerToken: "c", AdminToken: "a"}
+	t.Cleanup(func() { security = stubs.Security{} })
+	mux := http.NewServeMux()
+	mux.Handle("/", newHTTPHandler())
+	mux.HandleFunc("/admin", handle(stubs.CloseBroker, func(w http.ResponseWriter, r *http.Request) error {
+		writeJSON(w, http.StatusOK, turnReply{})
+		return nil
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"no token", "/game", "", http.StatusUnauthorized},
+		{"unknown token", "/game", "x", http.StatusUnauthorized},
+		{"controller", "/game", "c", http.StatusOK},
+		{"controller for an admin method", "/admin", "c", http.StatusForbidden},
+		{"admin", "/admin", "a", http.StatusOK},
+	}
+	for _, test := range tests {
+		res := call(t, server, http.MethodGet, test.path, test.token, "", nil)
+		if res.StatusCode != test.status {
+			t.Errorf("%v: expected %v, got %v", test.name, test.status, res.StatusCode)
+		}
+		if test.status == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%v: expected a WWW-Authenticate header", test.name)
+		}
+	}
+
+	// the scheme is not case sensitive, but must be Bearer
+	for header, status := range map[string]int{
+		"bearer c":  http.StatusOK,
+		"BEARER c":  http.StatusOK,
+		"Basic c":   http.StatusUnauthorized,
+		"Basic Yzo": http.StatusUnauthorized,
+		"c":         http.StatusUnauthorized,
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/game", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", header)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != status {
+			t.Errorf("Authorization %q: expected %v, got %v", header, status, res.StatusCode)
+		}
+	}
+
+	// the token may also be given as a query parameter
+	if res := call(t, server, http.MethodGet, "/game?token=c", "", "", nil); res.StatusCode != http.StatusOK {
+		t.Errorf("token parameter: expected 200, got %v", res.StatusCode)
+	}
+}
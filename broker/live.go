@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// The live view streams the game to browsers over a WebSocket at /live/ws.
+// Each turn is sent as a delta, built from the same SendWorldStateRequest that goes to the controller,
+// and so is each edit of a paused game, with the turn it was made after:
+//
+//	{"type": "delta", "turn": 12, "aliveCount": 300, "cells": [x0, y0, x1, y1, ...]}
+//
+// A client that cannot keep up has deltas dropped, and is then sent a keyframe of every alive cell
+// so that it can start again from a consistent world. A keyframe is also sent when a client connects,
+// and to every client when a new game starts:
+//
+//	{"type": "keyframe", "turn": 15, "width": 64, "height": 64, "aliveCount": 310, "cells": [...]}
+//
+// Clients may send {"command": "pause"}, "resume" or "step", which are answered with a
+// {"type": "state", ...} message, or "screenshot", which is answered with a binary message holding a PNG.
+
+// liveBufferSize is how many deltas may be waiting for a client before they are dropped.
+const liveBufferSize = 16
+
+var live = new(liveHub)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024 * 64,
+}
+
+// liveFrame is a message sent to the client.
+type liveFrame struct {
+	Type       string `json:"type"`
+	Turn       int    `json:"turn"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	AliveCount int    `json:"aliveCount"`
+	Cells      []int  `json:"cells,omitempty"`
+	Paused     bool   `json:"paused,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	seq int // orders deltas and keyframes, since an edit is sent with the same turn as the delta before it
+}
+
+// liveCommand is a message received from the client.
+type liveCommand struct {
+	Command string `json:"command"`
+}
+
+// liveSubscriber is a single connected client.
+type liveSubscriber struct {
+	deltas   chan liveFrame
+	replies  chan interface{} // a liveFrame, or the bytes of a PNG
+	resync   chan struct{}    // signalled when the client needs a keyframe
+	dropping bool             // deltas are being dropped until the next keyframe
+}
+
+// liveHub fans the world state after each turn out to every connected client.
+type liveHub struct {
+	mutex       sync.Mutex
+	subscribers map[*liveSubscriber]bool
+	seq         int // number of deltas published so far
+}
+
+func (h *liveHub) subscribe() *liveSubscriber {
+	sub := &liveSubscriber{
+		deltas:   make(chan liveFrame, liveBufferSize),
+		replies:  make(chan interface{}, 4),
+		resync:   make(chan struct{}, 1),
+		dropping: true,
+	}
+	sub.resync <- struct{}{} // start with a keyframe
+	h.mutex.Lock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[*liveSubscriber]bool)
+	}
+	h.subscribers[sub] = true
+	h.mutex.Unlock()
+	return sub
+}
+
+func (h *liveHub) unsubscribe(sub *liveSubscriber) {
+	h.mutex.Lock()
+	delete(h.subscribers, sub)
+	h.mutex.Unlock()
+}
+
+// watched reports whether anybody is connected, so that RunTurns can skip working out the deltas.
+func (h *liveHub) watched() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.subscribers) > 0
+}
+
+// publish queues a delta for every client without blocking. It must be called with mutex held,
+// which keeps it ordered with respect to the keyframes taken by keyframe.
+func (h *liveHub) publish(state stubs.SendWorldStateRequest) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.subscribers) == 0 {
+		return
+	}
+	h.seq++
+	frame := liveFrame{
+		Type:       "delta",
+		Turn:       state.CompletedTurns,
+		AliveCount: state.CellsCount,
+		Cells:      flattenCells(state.CellsFlipped),
+		seq:        h.seq,
+	}
+	for sub := range h.subscribers {
+		if sub.dropping {
+			continue
+		}
+		select {
+		case sub.deltas <- frame:
+		default:
+			// the client is too slow, so drop deltas until it has caught up with a keyframe
+			sub.dropping = true
+			sub.resync <- struct{}{}
+		}
+	}
+}
+
+// restart sends every client a keyframe of a new game, since it may differ in size from the last one and
+// deltas from the old game no longer apply. It must be called with mutex held once the new world is in place.
+func (h *liveHub) restart() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for sub := range h.subscribers {
+		if sub.dropping {
+			continue // a keyframe has already been asked for
+		}
+		sub.dropping = true
+		sub.resync <- struct{}{}
+	}
+}
+
+// keyframe takes a copy of every alive cell and resumes sending deltas to sub from that point on.
+func (h *liveHub) keyframe(sub *liveSubscriber) liveFrame {
+	mutex.Lock()
+	defer mutex.Unlock()
+	alive := calculateAliveCells()
+	h.mutex.Lock()
+	sub.dropping = false
+	seq := h.seq
+	h.mutex.Unlock()
+	return liveFrame{
+		Type:       "keyframe",
+		Turn:       turn,
+		Width:      width,
+		Height:     height,
+		AliveCount: len(alive),
+		Cells:      flattenCells(alive),
+		seq:        seq,
+	}
+}
+
+func flattenCells(cells []util.Cell) []int {
+	flat := make([]int, 0, 2*len(cells))
+	for _, c := range cells {
+		flat = append(flat, c.X, c.Y)
+	}
+	return flat
+}
+
+func handleLiveSocket(w http.ResponseWriter, r *http.Request) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil // Upgrade has already replied to the client
+	}
+	token, _ := requestToken(r) // handle has already checked it
+	sub := live.subscribe()
+	readerDone := make(chan struct{})
+	writerDone := make(chan struct{})
+	go readLiveCommands(conn, sub, token, readerDone, writerDone)
+	writeLiveFrames(conn, sub, readerDone)
+	close(writerDone)
+	live.unsubscribe(sub)
+	conn.Close()
+	return nil
+}
+
+// writeLiveFrames is the only goroutine that writes to conn, as websocket requires.
+func writeLiveFrames(conn *websocket.Conn, sub *liveSubscriber, done <-chan struct{}) {
+	sent := -1 // seq of the last frame sent
+	for {
+		var err error
+		select {
+		case frame := <-sub.deltas:
+			if frame.seq <= sent {
+				continue // already covered by a keyframe
+			}
+			sent = frame.seq
+			err = conn.WriteJSON(frame)
+		case <-sub.resync:
+			frame := live.keyframe(sub)
+			sent = frame.seq
+			err = conn.WriteJSON(frame)
+		case reply := <-sub.replies:
+			if png, ok := reply.([]byte); ok {
+				err = conn.WriteMessage(websocket.BinaryMessage, png)
+			} else {
+				err = conn.WriteJSON(reply)
+			}
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readLiveCommands carries out the client's commands until the connection is closed.
+func readLiveCommands(conn *websocket.Conn, sub *liveSubscriber, token string, done chan<- struct{}, writerDone <-chan struct{}) {
+	defer close(done)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var reply interface{}
+		var cmd liveCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			reply = liveFrame{Type: "error", Error: err.Error()}
+		} else {
+			reply = runLiveCommand(cmd.Command, token)
+		}
+		select {
+		case sub.replies <- reply:
+		case <-writerDone:
+			return
+		}
+	}
+}
+
+// liveCommands maps each command a client may send to the rpc method it stands in for.
+var liveCommands = map[string]string{
+	"pause":      stubs.Pause,
+	"resume":     stubs.Restart,
+	"step":       stubs.Step,
+	"screenshot": stubs.Screenshot,
+}
+
+// runLiveCommand calls the Broker method for a command, subject to the same permissions as over rpc.
+func runLiveCommand(command, token string) interface{} {
+	serviceMethod, ok := liveCommands[command]
+	if !ok {
+		return liveFrame{Type: "error", Error: fmt.Sprintf("unknown command %q", command)}
+	}
+	if err := security.Authorize(token, serviceMethod); err != nil {
+		return liveFrame{Type: "error", Error: err.Error()}
+	}
+
+	broker := new(Broker)
+	var turnDone int
+	var err error
+	switch command {
+	case "pause":
+		res := new(stubs.PauseResponse)
+		err = broker.Pause(stubs.PauseRequest{}, res)
+		turnDone = res.Turn
+	case "resume":
+		res := new(stubs.RestartResponse)
+		err = broker.Restart(stubs.RestartRequest{}, res)
+		turnDone = res.Turn
+	case "step":
+		res := new(stubs.StepResponse)
+		err = broker.Step(stubs.StepRequest{}, res)
+		turnDone = res.Turn
+	case "screenshot":
+		res := new(stubs.ScreenshotResponse)
+		var shot [][]byte
+		err = broker.Screenshot(stubs.ScreenshotRequest{}, res)
+		if err == nil {
+			shot, err = stubs.UnpackWorld(res.World, res.Packed)
+		}
+		if err == nil && len(shot) == 0 {
+			err = errors.New("there is no world to take a screenshot of")
+		}
+		if err == nil {
+			var png []byte
+			if png, err = encodePng(shot); err == nil {
+				return png
+			}
+		}
+	}
+	if err != nil {
+		return liveFrame{Type: "error", Error: err.Error()}
+	}
+
+	mutex.Lock()
+	isPaused := paused
+	mutex.Unlock()
+	return liveFrame{Type: "state", Turn: turnDone, Paused: isPaused}
+}
+
+func handleLivePage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, livePage)
+	return nil
+}
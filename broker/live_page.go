@@ -0,0 +1,96 @@
+package main
+
+// livePage is served at /live/ and draws the frames sent over /live/ws onto a canvas.
+// Any token in the page's own URL is passed on to the WebSocket.
+const livePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game of Life</title>
+<style>
+body { background: #222; color: #eee; font-family: sans-serif; }
+canvas { image-rendering: pixelated; border: 1px solid #555; max-width: 95vw; max-height: 85vh; }
+</style>
+</head>
+<body>
+<div>
+<button id="pause">Pause</button>
+<button id="resume">Resume</button>
+<button id="step">Step</button>
+<button id="screenshot">Screenshot</button>
+<span id="status">Connecting...</span>
+</div>
+<canvas id="world" width="1" height="1"></canvas>
+<script>
+var canvas = document.getElementById("world");
+var ctx = canvas.getContext("2d");
+var statusText = document.getElementById("status");
+var image = null;
+var paused = false;
+
+var params = new URLSearchParams(location.search);
+var url = (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/live/ws";
+if (params.get("token")) {
+	url += "?token=" + encodeURIComponent(params.get("token"));
+}
+var socket = new WebSocket(url);
+socket.binaryType = "blob";
+
+function flip(cells) {
+	for (var i = 0; i < cells.length; i += 2) {
+		var p = 4 * (cells[i + 1] * image.width + cells[i]);
+		var v = image.data[p] ? 0 : 255;
+		image.data[p] = image.data[p + 1] = image.data[p + 2] = v;
+	}
+}
+
+socket.onmessage = function (message) {
+	if (message.data instanceof Blob) {
+		var link = document.createElement("a");
+		link.href = URL.createObjectURL(message.data);
+		link.download = "screenshot.png";
+		link.click();
+		return;
+	}
+	var frame = JSON.parse(message.data);
+	switch (frame.type) {
+	case "keyframe":
+		canvas.width = Math.max(frame.width, 1);
+		canvas.height = Math.max(frame.height, 1);
+		image = ctx.createImageData(canvas.width, canvas.height);
+		for (var i = 3; i < image.data.length; i += 4) {
+			image.data[i] = 255;
+		}
+		flip(frame.cells || []);
+		break;
+	case "delta":
+		if (image) {
+			flip(frame.cells || []);
+		}
+		break;
+	case "state":
+		paused = frame.paused;
+		break;
+	case "error":
+		statusText.textContent = "Error: " + frame.error;
+		return;
+	}
+	if (image) {
+		ctx.putImageData(image, 0, 0);
+	}
+	statusText.textContent = "Turn " + frame.turn + (frame.type === "state" ? "" : ", " + frame.aliveCount + " alive") + (paused ? " (paused)" : "");
+};
+
+socket.onclose = function () {
+	statusText.textContent = "Disconnected";
+};
+
+["pause", "resume", "step", "screenshot"].forEach(function (command) {
+	document.getElementById(command).onclick = function () {
+		socket.send(JSON.stringify({command: command}));
+	};
+});
+</script>
+</body>
+</html>
+`
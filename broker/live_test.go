@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// setTestWorld installs a 4x4 world with a single alive cell at (1, 2).
+func setTestWorld(t *testing.T) {
+	mutex.Lock()
+	width, height, turn = 4, 4, 0
+	world = make([][]byte, height)
+	for i := range world {
+		world[i] = make([]byte, width)
+	}
+	world[2][1] = 255
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		width, height, turn, world, paused = 0, 0, 0, nil, false
+		mutex.Unlock()
+	})
+}
+
+// dialLive connects a client to the live view for the length of the test.
+func dialLive(t *testing.T) *websocket.Conn {
+	server := httptest.NewServer(newHTTPHandler())
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/live/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestLiveSocket checks that a client is sent a keyframe, then deltas, and can run commands.
+func TestLiveSocket(t *testing.T) {
+	setTestWorld(t)
+	conn := dialLive(t)
+
+	var frame liveFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "keyframe" || frame.Width != 4 || frame.Height != 4 || len(frame.Cells) != 2 || frame.Cells[0] != 1 || frame.Cells[1] != 2 {
+		t.Fatalf("expected a keyframe of the 4x4 world, got %+v", frame)
+	}
+
+	mutex.Lock()
+	turn = 1
+	live.publish(stubs.SendWorldStateRequest{
+		CellsFlipped:   []util.Cell{{X: 1, Y: 2}, {X: 3, Y: 3}},
+		CompletedTurns: 1,
+		CellsCount:     1,
+	})
+	mutex.Unlock()
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "delta" || frame.Turn != 1 || len(frame.Cells) != 4 {
+		t.Fatalf("expected the delta for turn 1, got %+v", frame)
+	}
+
+	if err := conn.WriteJSON(liveCommand{"pause"}); err != nil {
+		t.Fatal(err)
+	}
+	frame = liveFrame{}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "state" || !frame.Paused || frame.Turn != 1 {
+		t.Fatalf("expected a paused state at turn 1, got %+v", frame)
+	}
+
+	if err := conn.WriteJSON(liveCommand{"screenshot"}); err != nil {
+		t.Fatal(err)
+	}
+	kind, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != websocket.BinaryMessage || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Fatalf("expected a PNG screenshot, got message type %v", kind)
+	}
+}
+
+// TestLiveEdit checks that cells edited while the game is paused are sent to a client that is up to date,
+// although the delta has the same turn as the last one sent.
+func TestLiveEdit(t *testing.T) {
+	setTestWorld(t)
+	mutex.Lock()
+	running, paused = true, true
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		running, edited = false, false
+		mutex.Unlock()
+	})
+	conn := dialLive(t)
+
+	var frame liveFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "keyframe" || frame.Turn != 0 {
+		t.Fatalf("expected a keyframe at turn 0, got %+v", frame)
+	}
+
+	req := stubs.EditCellsRequest{Cells: []util.Cell{{X: 3, Y: 0}}}
+	if err := new(Broker).EditCells(req, new(stubs.EditCellsResponse)); err != nil {
+		t.Fatal(err)
+	}
+	frame = liveFrame{}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "delta" || frame.Turn != 0 || frame.AliveCount != 2 || !reflect.DeepEqual(frame.Cells, []int{3, 0}) {
+		t.Fatalf("expected a delta flipping (3, 0), got %+v", frame)
+	}
+}
+
+// TestLiveDropsFrames checks that a slow client has deltas dropped until it has been sent a keyframe.
+func TestLiveDropsFrames(t *testing.T) {
+	setTestWorld(t)
+	sub := live.subscribe()
+	defer live.unsubscribe(sub)
+	<-sub.resync
+	live.keyframe(sub)
+
+	mutex.Lock()
+	for i := 1; i <= liveBufferSize+5; i++ {
+		turn = i
+		live.publish(stubs.SendWorldStateRequest{CompletedTurns: i})
+	}
+	mutex.Unlock()
+
+	if len(sub.deltas) != liveBufferSize {
+		t.Fatalf("expected %v queued deltas, got %v", liveBufferSize, len(sub.deltas))
+	}
+	select {
+	case <-sub.resync:
+	default:
+		t.Fatal("expected a keyframe to be requested")
+	}
+	if frame := live.keyframe(sub); frame.Turn != liveBufferSize+5 {
+		t.Fatalf("expected a keyframe at turn %v, got %v", liveBufferSize+5, frame.Turn)
+	}
+
+	// once the stale deltas have been skipped, new ones are queued again
+	for len(sub.deltas) > 0 {
+		<-sub.deltas
+	}
+	mutex.Lock()
+	turn++
+	live.publish(stubs.SendWorldStateRequest{CompletedTurns: turn})
+	mutex.Unlock()
+	if len(sub.deltas) != 1 {
+		t.Fatalf("expected 1 queued delta after the keyframe, got %v", len(sub.deltas))
+	}
+}
+
+// TestLiveNewGame checks that a client still connected when a new game starts is sent a keyframe of it,
+// and then its deltas.
+func TestLiveNewGame(t *testing.T) {
+	startFakeServers(t)
+	conn := dialLive(t)
+	var frame liveFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{4, 6} {
+		world := make([][]byte, size)
+		for i := range world {
+			world[i] = make([]byte, size)
+		}
+		world[1][1] = 255
+		resultChan, err := startGame(stubs.RunGameRequest{Turns: 1000000000, Height: size, Width: size, Threads: 1, World: world}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gameDone := make(chan error)
+		go func() { gameDone <- awaitGame(resultChan, new(stubs.RunGameResponse)) }()
+
+		// deltas of the last game may still be on their way before the keyframe
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for frame.Type != "keyframe" || frame.Width != size {
+			frame = liveFrame{}
+			if err := conn.ReadJSON(&frame); err != nil {
+				t.Fatalf("%vx%v: expected a keyframe of the new game: %v", size, size, err)
+			}
+		}
+		keyframe := frame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("%vx%v: expected a delta after the keyframe: %v", size, size, err)
+		}
+		if frame.Type != "delta" || frame.Turn <= keyframe.Turn {
+			t.Errorf("%vx%v: expected a delta after turn %v, got %+v", size, size, keyframe.Turn, frame)
+		}
+
+		new(Broker).Quit(stubs.QuitRequest{}, new(stubs.QuitResponse))
+		if err := <-gameDone; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
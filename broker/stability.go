@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// cycleDetector finds the first turn at which the world repeats one of the worlds of the turns before it,
+// by keeping a hash and a copy of each of the last maxPeriod worlds. A still life repeats with period 1.
+type cycleDetector struct {
+	maxPeriod int
+	hashes    []uint64 // of the worlds of the last maxPeriod turns, oldest first
+	worlds    [][]byte // the cells of the same worlds, to tell a repeat from a hash collision
+}
+
+// newCycleDetector returns a detector for cycles with periods up to maxPeriod, starting from world.
+func newCycleDetector(maxPeriod int, world [][]byte) *cycleDetector {
+	d := &cycleDetector{maxPeriod: maxPeriod}
+	d.add(world)
+	return d
+}
+
+// add records the world of the next turn, and returns the period of the cycle it completes, or 0 if
+// it is not the same as any of the worlds recorded before it.
+// Worlds with the same hash are compared cell by cell, since skipping turns on a collision would give the
+// wrong final world.
+func (d *cycleDetector) add(world [][]byte) int {
+	h := hashWorld(world)
+	cells := flatten(world)
+	for i := len(d.hashes) - 1; i >= 0; i-- {
+		if d.hashes[i] == h && bytes.Equal(d.worlds[i], cells) {
+			return len(d.hashes) - i
+		}
+	}
+	if len(d.hashes) == d.maxPeriod {
+		d.hashes = d.hashes[1:]
+		d.worlds = d.worlds[1:]
+	}
+	d.hashes = append(d.hashes, h)
+	d.worlds = append(d.worlds, cells)
+	return 0
+}
+
+// hashWorld returns a 64-bit FNV-1a hash of world, which is compared before the cells themselves.
+func hashWorld(world [][]byte) uint64 {
+	h := fnv.New64a()
+	for _, row := range world {
+		h.Write(row)
+	}
+	return h.Sum64()
+}
+
+// flatten returns a copy of the cells of world in a single slice.
+func flatten(world [][]byte) []byte {
+	var cells []byte
+	if len(world) > 0 {
+		cells = make([]byte, 0, len(world)*len(world[0]))
+	}
+	for _, row := range world {
+		cells = append(cells, row...)
+	}
+	return cells
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+// TestCycleDetector checks that the first repeat of a world is found, along with the period it repeats after,
+// as long as the period is no longer than the longest looked for.
+func TestCycleDetector(t *testing.T) {
+	// a world with a single alive cell at x, which is enough to tell worlds apart
+	w := func(x int) [][]byte {
+		world := [][]byte{make([]byte, 4), make([]byte, 4)}
+		world[1][x] = 255
+		return world
+	}
+
+	tests := []struct {
+		name      string
+		maxPeriod int
+		worlds    []int // alive cell of each turn's world, from turn 0
+		period    int
+		turn      int // first repeat
+	}{
+		{"still life", 4, []int{0, 1, 1, 1}, 1, 2},
+		{"period 2", 4, []int{0, 1, 2, 3, 2, 3}, 2, 4},
+		{"period 3 from the start", 3, []int{0, 1, 2, 0}, 3, 3},
+		{"period too long", 2, []int{0, 1, 2, 0, 1, 2}, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newCycleDetector(test.maxPeriod, w(test.worlds[0]))
+			for turn, x := range test.worlds[1:] {
+				turn++
+				if period := d.add(w(x)); period != 0 {
+					if period != test.period || turn != test.turn {
+						t.Errorf("expected period %v at turn %v, got period %v at turn %v", test.period, test.turn, period, turn)
+					}
+					return
+				}
+			}
+			if test.period != 0 {
+				t.Errorf("expected period %v at turn %v, found no repeat", test.period, test.turn)
+			}
+		})
+	}
+}
+
+// TestCycleDetectorCollision checks that a world whose hash matches an earlier world is not taken as a repeat
+// unless its cells are the same too.
+func TestCycleDetectorCollision(t *testing.T) {
+	a := [][]byte{{255, 0}, {0, 0}}
+	b := [][]byte{{0, 255}, {0, 0}}
+	d := newCycleDetector(4, a)
+	// make the recorded hash of a collide with that of b
+	d.hashes[0] = hashWorld(b)
+	if period := d.add(b); period != 0 {
+		t.Fatalf("expected a collision not to be taken as a repeat, got period %v", period)
+	}
+	if period := d.add(b); period != 1 {
+		t.Errorf("expected b to repeat with period 1, got %v", period)
+	}
+}
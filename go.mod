@@ -1,5 +1,8 @@
 module uk.ac.bris.cs/gameoflife
 
-go 1.12
+go 1.15
 
-require github.com/veandco/go-sdl2 v0.4.4
+require (
+	github.com/gorilla/websocket v1.5.0
+	github.com/veandco/go-sdl2 v0.4.4
+)
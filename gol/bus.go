@@ -0,0 +1,152 @@
+package gol
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Policy decides what happens to an event for a subscriber whose queue is full.
+type Policy int
+
+const (
+	// Block waits for the subscriber to make room, which holds up every other subscriber.
+	Block Policy = iota
+	// DropOldest throws away the oldest queued event to make room.
+	DropOldest
+	// Coalesce drops the latest queued event of the same type and queues the new event at the back, for events
+	// that only report the latest state (AliveCellsCount, TurnComplete and TurnTiming), so that it still follows
+	// the events queued before it, such as the flips of its turn. Any other event waits, as with Block.
+	Coalesce
+)
+
+// EventBus passes every event sent by Run on to any number of subscribers, such as the window, a logger and a
+// recorder, each with its own filter and queue.
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers []*subscriber
+}
+
+// NewEventBus returns a bus with no subscribers.
+func NewEventBus() *EventBus {
+	return new(EventBus)
+}
+
+// Subscribe returns a channel of the events that filter accepts, or of every event if filter is nil.
+// Up to size events are queued for the subscriber before policy applies. The channel is closed after the last event.
+// Subscribers should be added before Run is called, so that none of the events are missed.
+func (b *EventBus) Subscribe(filter func(Event) bool, policy Policy, size int) <-chan Event {
+	if size < 1 {
+		size = 1
+	}
+	s := &subscriber{filter: filter, policy: policy, size: size, out: make(chan Event)}
+	s.cond = sync.NewCond(&s.mutex)
+	go s.pump()
+	b.mutex.Lock()
+	b.subscribers = append(b.subscribers, s)
+	b.mutex.Unlock()
+	return s.out
+}
+
+// Run passes each event on to the subscribers until events is closed, then closes their channels
+// once they have been sent everything queued for them.
+func (b *EventBus) Run(events <-chan Event) {
+	for event := range events {
+		b.mutex.Lock()
+		for _, s := range b.subscribers {
+			if s.filter == nil || s.filter(event) {
+				s.push(event)
+			}
+		}
+		b.mutex.Unlock()
+	}
+	b.mutex.Lock()
+	for _, s := range b.subscribers {
+		s.close()
+	}
+	b.mutex.Unlock()
+}
+
+// Without returns a filter that accepts every event other than those of the same types as examples,
+// for example Without(CellFlipped{}, CellsFlipped{}) for a subscriber that does not draw the world.
+func Without(examples ...Event) func(Event) bool {
+	types := make(map[reflect.Type]bool)
+	for _, e := range examples {
+		types[reflect.TypeOf(e)] = true
+	}
+	return func(event Event) bool {
+		return !types[reflect.TypeOf(event)]
+	}
+}
+
+// subscriber queues events for a single subscriber, which pump sends on to out.
+type subscriber struct {
+	filter func(Event) bool
+	policy Policy
+	size   int
+	out    chan Event
+
+	mutex  sync.Mutex
+	cond   *sync.Cond // signalled when an event is queued or taken off the queue, or the bus closes
+	queue  []Event
+	closed bool
+}
+
+func (s *subscriber) push(event Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for len(s.queue) >= s.size {
+		if s.policy == DropOldest {
+			s.queue = s.queue[1:]
+			break
+		}
+		if s.policy == Coalesce && s.coalesce(event) {
+			break
+		}
+		s.cond.Wait()
+	}
+	s.queue = append(s.queue, event)
+	s.cond.Broadcast()
+}
+
+// coalesce takes the latest queued event of the same type as event off the queue, if it is a type that can be
+// coalesced, to make room for event at the back.
+func (s *subscriber) coalesce(event Event) bool {
+	switch event.(type) {
+	case AliveCellsCount, TurnComplete, TurnTiming:
+	default:
+		return false
+	}
+	for i := len(s.queue) - 1; i >= 0; i-- {
+		if reflect.TypeOf(s.queue[i]) == reflect.TypeOf(event) {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriber) close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+}
+
+func (s *subscriber) pump() {
+	for {
+		s.mutex.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 {
+			s.mutex.Unlock()
+			close(s.out)
+			return
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+		s.out <- event
+	}
+}
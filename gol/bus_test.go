@@ -0,0 +1,137 @@
+package gol
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestEventBus checks that every subscriber is sent the events its filter accepts, in order.
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	all := bus.Subscribe(nil, Block, 1)
+	counts := bus.Subscribe(Without(CellFlipped{}, TurnComplete{}), Block, 1)
+
+	events := make(chan Event)
+	go bus.Run(events)
+	go func() {
+		for turn := 1; turn <= 100; turn++ {
+			events <- CellFlipped{turn, util.Cell{X: turn, Y: 0}}
+			events <- TurnComplete{turn}
+			events <- AliveCellsCount{turn, turn}
+		}
+		close(events)
+	}()
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for event := range counts {
+			if _, ok := event.(AliveCellsCount); !ok {
+				t.Errorf("filtered subscriber was sent %#v", event)
+			}
+			n++
+		}
+		done <- n
+	}()
+	n := 0
+	for event := range all {
+		if turn := n/3 + 1; event.GetCompletedTurns() != turn {
+			t.Fatalf("event %v is for turn %v, expected %v", n, event.GetCompletedTurns(), turn)
+		}
+		n++
+	}
+	if n != 300 {
+		t.Errorf("expected 300 events, got %v", n)
+	}
+	if n := <-done; n != 100 {
+		t.Errorf("expected 100 AliveCellsCount events, got %v", n)
+	}
+}
+
+// TestEventBusPolicies checks what each policy does while a subscriber is not taking its events.
+func TestEventBusPolicies(t *testing.T) {
+	bus := NewEventBus()
+	dropping := bus.Subscribe(nil, DropOldest, 3)
+	coalescing := bus.Subscribe(nil, Coalesce, 3)
+
+	events := make(chan Event)
+	finished := make(chan struct{})
+	go func() {
+		bus.Run(events)
+		close(finished)
+	}()
+	// neither subscriber is reading, so neither policy may hold up the bus for the counts:
+	// the bus only takes StateChange once it has passed on the last of them
+	for turn := 1; turn <= 10; turn++ {
+		events <- AliveCellsCount{turn, turn}
+	}
+	events <- StateChange{10, Quitting}
+	close(events)
+
+	// Quitting waits for room in the coalescing queue, so both subscribers are read at once
+	collected := make(chan []Event)
+	go func() {
+		var coalesced []Event
+		for event := range coalescing {
+			coalesced = append(coalesced, event)
+		}
+		collected <- coalesced
+	}()
+
+	// the pump may hold one earlier event while it waits for the subscriber, depending on when it first ran
+	var dropped []Event
+	for event := range dropping {
+		dropped = append(dropped, event)
+	}
+	latest := []Event{AliveCellsCount{9, 9}, AliveCellsCount{10, 10}, StateChange{10, Quitting}}
+	if len(dropped) < 3 || len(dropped) > 4 {
+		t.Fatalf("expected the latest three events, got %v", dropped)
+	}
+	for i, event := range dropped[len(dropped)-3:] {
+		if event != latest[i] {
+			t.Errorf("expected the latest three events, got %v", dropped)
+		}
+	}
+
+	coalesced := <-collected
+	n := len(coalesced)
+	// which counts in between survive depends on when the pump ran, but the first is never coalesced away
+	// and the rest must still be in the order they were sent
+	if n >= 11 || n < 3 || coalesced[0] != (AliveCellsCount{1, 1}) ||
+		coalesced[n-2] != (AliveCellsCount{10, 10}) || coalesced[n-1] != (StateChange{10, Quitting}) {
+		t.Fatalf("expected the counts to be coalesced into the latest, followed by Quitting, got %v", coalesced)
+	}
+	for i := 1; i < n-1; i++ {
+		if coalesced[i].GetCompletedTurns() <= coalesced[i-1].GetCompletedTurns() {
+			t.Errorf("coalesced events are out of order: %v", coalesced)
+		}
+	}
+	<-finished
+}
+
+// TestCoalesceOrder checks the exact order of a full coalescing queue: a coalesced event moves to the back,
+// behind the flips queued before it, rather than taking the place of the event it replaces.
+func TestCoalesceOrder(t *testing.T) {
+	s := &subscriber{policy: Coalesce, size: 3}
+	s.cond = sync.NewCond(&s.mutex)
+	s.push(AliveCellsCount{1, 1})
+	s.push(CellFlipped{2, util.Cell{X: 2, Y: 0}})
+	s.push(TurnComplete{2})
+
+	s.push(AliveCellsCount{2, 2})
+	expected := []Event{CellFlipped{2, util.Cell{X: 2, Y: 0}}, TurnComplete{2}, AliveCellsCount{2, 2}}
+	if !reflect.DeepEqual(s.queue, expected) {
+		t.Fatalf("expected %v, got %v", expected, s.queue)
+	}
+
+	s.queue = s.queue[1:]
+	s.push(CellFlipped{3, util.Cell{X: 3, Y: 0}})
+	s.push(TurnComplete{3})
+	expected = []Event{AliveCellsCount{2, 2}, CellFlipped{3, util.Cell{X: 3, Y: 0}}, TurnComplete{3}}
+	if !reflect.DeepEqual(s.queue, expected) {
+		t.Fatalf("expected %v, got %v", expected, s.queue)
+	}
+}
@@ -0,0 +1,63 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Plaintext (.cells) patterns draw the pattern directly, one row per line, with ! starting a comment:
+//
+//	!Name: Glider
+//	.O
+//	..O
+//	OOO
+//
+// Rows may be shorter than the widest row, in which case the rest of the row is dead.
+// See https://conwaylife.com/wiki/Plaintext
+
+// readCells reads a plaintext pattern and places it in the centre of an otherwise dead world of the given size.
+func readCells(r io.Reader, width, height int) ([][]byte, error) {
+	scanner := bufio.NewScanner(r)
+	var rows []string
+	patternWidth := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		rows = append(rows, line)
+		if len(line) > patternWidth {
+			patternWidth = len(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// blank lines at the end are not part of the pattern
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("cells: empty pattern")
+	}
+
+	world, offsetX, offsetY, err := centredWorld(patternWidth, len(rows), width, height)
+	if err != nil {
+		return nil, fmt.Errorf("cells: %w", err)
+	}
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case '.':
+			case 'O', '*':
+				world[offsetY+y][offsetX+x] = 255
+			default:
+				return nil, fmt.Errorf("cells: unexpected %q on row %v", c, y+1)
+			}
+		}
+	}
+	return world, nil
+}
@@ -1,9 +1,11 @@
 package gol
 
 import (
-	"log"
 	"net"
 	"net/rpc"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"uk.ac.bris.cs/gameoflife/stubs"
@@ -17,14 +19,18 @@ type distributorChannels struct {
 	ioCommand  chan<- ioCommand
 	ioIdle     <-chan bool
 	ioFilename chan<- string
-	ioOutput   chan<- uint8
-	ioInput    <-chan uint8
+	ioOutput   chan<- [][]byte
+	ioInput    <-chan [][]byte
+	ioResult   <-chan error
 	keyPresses <-chan rune
 }
 
 var (
 	wg             sync.WaitGroup
 	worldStateChan chan stubs.SendWorldStateRequest
+	workerChan     chan Event // WorkerJoined and WorkerLost events from the broker
+	ioMutex        sync.Mutex // held for the whole of each save, so that screenshots and the final save take turns
+	brokerAddr     = "127.0.0.1:8030"
 )
 
 type Controller struct{}
@@ -36,19 +42,42 @@ func (c *Controller) SendWorldState(req stubs.SendWorldStateRequest, res *stubs.
 	return
 }
 
+func (c *Controller) SendWorkerChange(req stubs.WorkerChangeRequest, res *stubs.WorkerChangeResponse) (err error) {
+	wg.Add(1)
+	defer wg.Done()
+	if req.Joined {
+		workerChan <- WorkerJoined{req.CompletedTurns, req.Address, req.StartY, req.EndY}
+	} else {
+		workerChan <- WorkerLost{req.CompletedTurns, req.Address, req.StartY, req.EndY, req.Reason}
+	}
+	return
+}
+
 // define makeReadyToDialCall to tell broker it is safe to dial the client
-func makeReadyToDialCall(client *rpc.Client, portStr string, resultChan chan<- stubs.ReadyToDialResponse) {
+func makeReadyToDialCall(client *rpc.Client, portStr string, p Params) (stubs.ReadyToDialResponse, error) {
 	req := stubs.ReadyToDialRequest{
 		S:    "controller is connected to broker",
 		Port: portStr,
 	}
+	if p.Compression != "" {
+		req.Compression = []string{p.Compression}
+	}
 	res := new(stubs.ReadyToDialResponse)
-	client.Call(stubs.ReadyToDial, req, res)
+	err := stubs.CallTimeout(client, stubs.ReadyToDial, req, res, p.RPCTimeout, nil)
 	// fmt.Println(res.S)
-	resultChan <- *res
+	return *res, err
+}
+
+// runGameResult holds the broker's reply to RunGame, or the reason there is none.
+type runGameResult struct {
+	res stubs.RunGameResponse
+	err error
 }
 
-func makeRunGameCall(client *rpc.Client, world [][]byte, p Params, resultChan chan<- stubs.RunGameResponse) {
+// makeRunGameCall has no deadline since the game can run for any length of time,
+// instead it gives up as soon as cancel is closed.
+// The world is packed with encoding, if the broker has agreed to one.
+func makeRunGameCall(client *rpc.Client, world [][]byte, p Params, encoding string, cancel <-chan struct{}, resultChan chan<- runGameResult) {
 	defer wg.Done()
 	req := stubs.RunGameRequest{
 		Turns:   p.Turns,
@@ -56,83 +85,192 @@ func makeRunGameCall(client *rpc.Client, world [][]byte, p Params, resultChan ch
 		Width:   p.ImageWidth,
 		Threads: p.Threads,
 		World:   world,
+
+		Compression: encoding,
+
+		DetectPeriod: p.DetectPeriod,
+		SkipCycles:   p.SkipCycles,
+
+		RegionColumns: p.RegionColumns,
+		RegionRows:    p.RegionRows,
+	}
+	var err error
+	if encoding != "" {
+		req.Packed, err = stubs.PackWorld(world, encoding)
+		if err != nil {
+			resultChan <- runGameResult{err: err}
+			return
+		}
+		req.World = nil
+		fmt.Println("Sending world:", req.Packed)
 	}
 	res := new(stubs.RunGameResponse)
-	client.Call(stubs.RunGame, req, res)
-	resultChan <- *res
+	err = stubs.CallTimeout(client, stubs.RunGame, req, res, 0, cancel)
+	if err == nil {
+		res.World, err = stubs.UnpackWorld(res.World, res.Packed)
+	}
+	resultChan <- runGameResult{*res, err}
 }
 
-func makeAliveCellsCountCall(client *rpc.Client, resultChan chan<- stubs.AliveCellsCountResponse) {
+func makeAliveCellsCountCall(client *rpc.Client, timeout time.Duration) (stubs.AliveCellsCountResponse, error) {
 	req := stubs.AliveCellsCountRequest{}
 	res := new(stubs.AliveCellsCountResponse)
-	client.Call(stubs.AliveCellsCount, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.AliveCellsCount, req, res, timeout, nil)
+	return *res, err
 }
 
-func makeScreenshotCall(client *rpc.Client, resultChan chan<- stubs.ScreenshotResponse) {
-	req := stubs.ScreenshotRequest{}
+func makeScreenshotCall(client *rpc.Client, encoding string, timeout time.Duration) (stubs.ScreenshotResponse, error) {
+	req := stubs.ScreenshotRequest{Compression: encoding}
 	res := new(stubs.ScreenshotResponse)
-	client.Call(stubs.Screenshot, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.Screenshot, req, res, timeout, nil)
+	if err == nil {
+		res.World, err = stubs.UnpackWorld(res.World, res.Packed)
+	}
+	return *res, err
 }
 
-func makeQuitCall(client *rpc.Client, resultChan chan<- stubs.QuitResponse) {
+func makeCensusCall(client *rpc.Client, timeout time.Duration) (stubs.CensusResponse, error) {
+	req := stubs.CensusRequest{}
+	res := new(stubs.CensusResponse)
+	err := stubs.CallTimeout(client, stubs.Census, req, res, timeout, nil)
+	return *res, err
+}
+
+func makeRegionStatsCall(client *rpc.Client, timeout time.Duration) (stubs.RegionStatsResponse, error) {
+	req := stubs.RegionStatsRequest{}
+	res := new(stubs.RegionStatsResponse)
+	err := stubs.CallTimeout(client, stubs.RegionStats, req, res, timeout, nil)
+	return *res, err
+}
+
+func makeEditCellsCall(client *rpc.Client, cells []util.Cell, timeout time.Duration) (stubs.EditCellsResponse, error) {
+	req := stubs.EditCellsRequest{Cells: cells}
+	res := new(stubs.EditCellsResponse)
+	err := stubs.CallTimeout(client, stubs.EditCells, req, res, timeout, nil)
+	return *res, err
+}
+
+func makeQuitCall(client *rpc.Client, timeout time.Duration) (stubs.QuitResponse, error) {
 	req := stubs.QuitRequest{}
 	res := new(stubs.QuitResponse)
-	client.Call(stubs.Quit, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.Quit, req, res, timeout, nil)
+	return *res, err
 }
 
-func makeCloseBrokerCall(client *rpc.Client) {
+// makeCloseBrokerCall dials its own connection since closing the broker needs the admin role
+func makeCloseBrokerCall(broker string, p Params) error {
+	client, err := p.Security.Dial(broker, stubs.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
 	req := stubs.CloseBrokerRequest{}
 	res := new(stubs.CloseBrokerResponse)
-	client.Call(stubs.CloseBroker, req, res)
+	return stubs.CallTimeout(client, stubs.CloseBroker, req, res, p.RPCTimeout, nil)
 }
 
-func makePauseCall(client *rpc.Client, resultChan chan<- stubs.PauseResponse) {
+func makePauseCall(client *rpc.Client, timeout time.Duration) (stubs.PauseResponse, error) {
 	req := stubs.PauseRequest{}
 	res := new(stubs.PauseResponse)
-	client.Call(stubs.Pause, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.Pause, req, res, timeout, nil)
+	return *res, err
 }
 
-func makeRestartCall(client *rpc.Client, resultChan chan<- stubs.RestartResponse) {
+func makeRestartCall(client *rpc.Client, timeout time.Duration) (stubs.RestartResponse, error) {
 	req := stubs.RestartRequest{}
 	res := new(stubs.RestartResponse)
-	client.Call(stubs.Restart, req, res)
-	resultChan <- *res
+	err := stubs.CallTimeout(client, stubs.Restart, req, res, timeout, nil)
+	return *res, err
 }
 
-func makeTickerGo(client *rpc.Client, ticker *time.Ticker, world [][]byte, p Params) (int, int) {
-	request := stubs.TickerRequest{
-		TickerChan: ticker.C,
-		Height:     p.ImageHeight,
-		Width:      p.ImageWidth,
-		World:      world,
+// sendFlips sends the cells flipped by a turn as a single CellsFlipped event if p.BatchFlips is set.
+// Otherwise it sends a CellFlipped event for each cell, which is slow for a busy world and means there is
+// a delay between pressing pause and the sdl pausing.
+func sendFlips(p Params, events chan<- Event, completedTurns int, cells []util.Cell) {
+	if p.BatchFlips {
+		if len(cells) > 0 {
+			events <- CellsFlipped{completedTurns, cells}
+		}
+		return
+	}
+	for _, cell := range cells {
+		events <- CellFlipped{
+			CompletedTurns: completedTurns,
+			Cell:           cell,
+		}
 	}
-	response := new(stubs.TickerResponse)
-	client.Call(stubs.ReturnAlive, request, response)
+}
 
-	return response.CompletedTurns, response.CellsCount
+// turnTiming returns the TurnTiming event for a turn reported by the broker.
+func turnTiming(s stubs.SendWorldStateRequest) TurnTiming {
+	timing := TurnTiming{
+		CompletedTurns: s.CompletedTurns,
+		Compute:        s.Timing.Compute,
+		Network:        s.Timing.Network,
+		Assembly:       s.Timing.Assembly,
+	}
+	if s.Timing.Interval > 0 {
+		timing.TurnsPerSecond = float64(time.Second) / float64(s.Timing.Interval)
+	}
+	return timing
 }
 
 func distributor(p Params, c distributorChannels) {
-	broker := "127.0.0.1:8030"
+	// read in image
+	filename := fmt.Sprintf("images/%vx%v.pgm", p.ImageWidth, p.ImageHeight)
+	if p.Input != "" {
+		filename = p.Input
+	} else if p.Soup != nil || len(p.Placements) > 0 {
+		// the world is generated, or the patterns are placed in an otherwise dead world
+		filename = ""
+	}
+	c.ioCommand <- ioInput
+	c.ioFilename <- filename
+	if err := <-c.ioResult; err != nil {
+		// there is no world to run the game on
+		c.events <- IOFailed{0, filename, err}
+		c.events <- StateChange{0, Quitting}
+		close(c.events)
+		return
+	}
+
+	world := <-c.ioInput
+
+	// send initial CellFlipped events for sdl
+	var alive []util.Cell
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if world[y][x] == 255 {
+				alive = append(alive, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	sendFlips(p, c.events, 0, alive)
+
+	broker := brokerAddr
 	fmt.Println("Broker: ", broker)
 
+	// abandon reports a game that could not be started, so that the window closes rather than waiting for it
+	abandon := func(reason string, err error) {
+		fmt.Println(reason, err)
+		c.events <- StateChange{0, Quitting}
+		close(c.events)
+	}
+
 	// dial Broker address that has been passed
-	client, err := rpc.Dial("tcp", broker)
+	client, err := p.Security.Dial(broker, stubs.RoleController)
 	if err != nil {
-		log.Fatal("dialing:", err)
+		abandon("Error dialling the broker:", err)
+		return
 	}
 	defer client.Close()
 
 	rpc.Register(&Controller{})
 
 	// listen on dynamically assigned port
-	listener, err := net.Listen("tcp", ":0")
+	listener, err := p.Security.Listen(":0")
 	if err != nil {
-		fmt.Println("Error starting Controller:", err)
+		abandon("Error starting Controller:", err)
 		return
 	}
 
@@ -143,6 +281,7 @@ func distributor(p Params, c distributorChannels) {
 	}
 
 	worldStateChan = make(chan stubs.SendWorldStateRequest, 1000000)
+	workerChan = make(chan Event, 100)
 
 	// start listening to broker on dynamically assigned port
 	go func() {
@@ -151,102 +290,99 @@ func distributor(p Params, c distributorChannels) {
 		fmt.Println("Controller listening on", listener.Addr())
 
 		// Accept connections and serve them
-		rpc.Accept(listener)
+		p.Security.Accept(listener)
 	}()
 
 	// send request to broker to say broker can dial the client, passing in port number
-	readyToDialResultChannel := make(chan stubs.ReadyToDialResponse)
-	go makeReadyToDialCall(client, portStr, readyToDialResultChannel)
-
-	// wait for response to say the broker has dialled client successfully (2-way comms is now available)
-	<-readyToDialResultChannel
-
-	// read in image
-	filename := fmt.Sprintf("%vx%v", p.ImageWidth, p.ImageHeight)
-	c.ioCommand <- ioInput
-	c.ioFilename <- filename
-
-	// Create a 2D slice to store the world.
-	world := make([][]byte, p.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, p.ImageWidth)
+	// and wait for response to say the broker has dialled client successfully (2-way comms is now available)
+	readyToDialResult, err := makeReadyToDialCall(client, portStr, p)
+	if err != nil {
+		listener.Close()
+		abandon("Error connecting to the broker:", err)
+		return
 	}
 
-	// send initial CellFlipped events for sdl
-	for y := 0; y < p.ImageHeight; y++ {
-		for x := 0; x < p.ImageWidth; x++ {
-			world[y][x] = <-c.ioInput
-			if world[y][x] == 255 {
-				c.events <- CellFlipped{
-					CompletedTurns: 0,
-					Cell:           util.Cell{X: x, Y: y},
-				}
-			}
+	stopListening := make(chan struct{})
+	listenerDone := make(chan struct{})
+	edited := make(chan CellsEdited) // edits made by the broker, passed to the listener to keep them in turn order
+
+	var rec *recorder
+	if p.Record != nil {
+		if rec, err = newRecorder(p, world); err != nil {
+			c.events <- IOFailed{0, filepath.Join(p.OutputDir, "frames"), err}
+			rec = nil
+		}
+	}
+	// record passes a turn to the recorder, which stops recording at the first error
+	record := func(s stubs.SendWorldStateRequest) {
+		if rec == nil {
+			return
+		}
+		if err := rec.turnComplete(s.CompletedTurns, s.CellsFlipped); err != nil {
+			c.events <- IOFailed{s.CompletedTurns, filepath.Join(p.OutputDir, "frames"), err}
+			rec = nil
 		}
 	}
-	finished := false
-	var finalWorld [][]byte
-	go func() {
-		finalWorld = makeCall(client, world, p)
-		finished = true
-	}()
 
-	ticker := time.NewTicker(2 * time.Second)
+	// turnComplete records a turn and sends its events
+	lastTurn := 0 // last turn completed, read once the listener is done
+	turnComplete := func(s stubs.SendWorldStateRequest) {
+		lastTurn = s.CompletedTurns
+		record(s)
 
-	var completedTurns, cellCount int
-	go func() {
-		for {
-			if finished {
-				fmt.Println("wrong")
-				break
-			} else {
-				fmt.Print("hi")
-				completedTurns, cellCount = makeTickerGo(client, ticker, world, p)
-				fmt.Println("sending")
-				c.events <- AliveCellsCount{
-					CompletedTurns: completedTurns,
-					CellsCount:     cellCount,
-				}
-			}
+		// send CellFlipped events
+		sendFlips(p, c.events, s.CompletedTurns, s.CellsFlipped)
+
+		// send TurnComplete event
+		c.events <- TurnComplete{
+			CompletedTurns: s.CompletedTurns,
 		}
-	}()
 
-	for {
-		if finished {
-			ticker.Stop()
-			alive := calculateAliveCells(p, finalWorld)
-			c.events <- FinalTurnComplete{
-				CompletedTurns: p.Turns,
-				Alive:          alive,
-			}
+		if s.Period > 0 {
+			c.events <- StabilityReached{s.CompletedTurns, s.Period, s.FirstTurn}
+		}
 
-	stopListening := make(chan struct{})
+		// turns skipped over once the world repeats are not timed
+		if p.ReportTiming && s.Timing.Interval > 0 {
+			c.events <- turnTiming(s)
+		}
+	}
 
 	// receive world state updates after every turn and send the data down the events channel
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer close(listenerDone)
 		for {
 			select {
 			case s := <-worldStateChan:
+				turnComplete(s)
 
-				// ! sending these events is slow and means there is a delay between pressing pause and the sdl pausing
+			case e := <-workerChan:
+				c.events <- e
 
-				// send CellFlipped events
-				for _, cell := range s.CellsFlipped {
-					c.events <- CellFlipped{
-						CompletedTurns: s.CompletedTurns,
-						Cell:           cell,
-					}
-				}
-
-				// send TurnComplete event
-				c.events <- TurnComplete{
-					CompletedTurns: s.CompletedTurns,
+			case e := <-edited:
+				if rec != nil {
+					rec.edit(e.Cells)
 				}
+				c.events <- e
 
 			// goroutine needs to receive signal from channel to stop executing
 			case <-stopListening:
+				for len(worldStateChan) > 0 {
+					turnComplete(<-worldStateChan)
+				}
+				// a lost worker is reported just before the game ends
+				for len(workerChan) > 0 {
+					c.events <- <-workerChan
+				}
+				if rec != nil {
+					if filename, err := rec.finish(); err != nil {
+						c.events <- IOFailed{rec.lastTurn, filepath.Join(p.OutputDir, filename), err}
+					} else if filename != "" {
+						c.events <- ImageOutputComplete{rec.lastTurn, filepath.Join(p.OutputDir, filename)}
+					}
+				}
 				return
 			}
 		}
@@ -255,78 +391,172 @@ func distributor(p Params, c distributorChannels) {
 	// start ticker
 	ticker := time.NewTicker(2 * time.Second)
 
+	// closed to stop waiting for RunGame when the broker can no longer be reached
+	cancelRunGame := make(chan struct{})
+	var cancelOnce sync.Once
+	cancel := func(err error) {
+		fmt.Println("Abandoning game:", err)
+		cancelOnce.Do(func() { close(cancelRunGame) })
+	}
+
 	wg.Add(1)
-	runGameResultChannel := make(chan stubs.RunGameResponse)
-	go makeRunGameCall(client, world, p, runGameResultChannel)
+	runGameResultChannel := make(chan runGameResult)
+	go makeRunGameCall(client, world, p, readyToDialResult.Compression, cancelRunGame, runGameResultChannel)
 
-	aliveCellsCountResultChannel := make(chan stubs.AliveCellsCountResponse)
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
-				go makeAliveCellsCountCall(client, aliveCellsCountResultChannel)
-				result := <-aliveCellsCountResultChannel
+				result, err := makeAliveCellsCountCall(client, p.RPCTimeout)
+				if err != nil {
+					cancel(err)
+					return
+				}
 				c.events <- AliveCellsCount{
 					CompletedTurns: result.CompletedTurns,
 					CellsCount:     result.CellsCount,
 				}
+				if p.RegionColumns > 0 && p.RegionRows > 0 {
+					regions, err := makeRegionStatsCall(client, p.RPCTimeout)
+					if err != nil {
+						cancel(err)
+						return
+					}
+					c.events <- RegionsUpdated{regions.CompletedTurns, regions.Stats}
+				}
+			case <-cancelRunGame:
+				return
 			}
 		}
 	}()
 
 	paused := false // stores whether execution has been paused
 
+	// quit stops the game on the broker, resuming it first since a paused broker cannot finish its turn
+	quit := func() error {
+		if paused {
+			if _, err := makeRestartCall(client, p.RPCTimeout); err != nil {
+				return err
+			}
+			paused = false
+		}
+		_, err := makeQuitCall(client, p.RPCTimeout)
+		return err
+	}
+
+	// edit asks the broker to toggle cells of the paused world.
+	// Edits that cannot be made are dropped, since the display only shows cells the broker has flipped.
+	edit := func(cells []util.Cell) {
+		result, err := makeEditCellsCall(client, cells, p.RPCTimeout)
+		if err != nil {
+			fmt.Println("Edit failed:", err)
+			return
+		}
+		if len(result.CellsFlipped) > 0 {
+			select {
+			case edited <- CellsEdited{result.CompletedTurns, result.CellsFlipped}:
+			case <-listenerDone:
+			}
+		}
+	}
+
 	// listen for keypresses
 	go func() {
 		for {
 			select {
 			case key := <-c.keyPresses:
+				// edits are sent before the key pressed after them, so they are made first
+				for pending := true; pending; {
+					select {
+					case cells := <-p.Edits:
+						edit(cells)
+					default:
+						pending = false
+					}
+				}
 				switch key {
 				case 's':
-					pgmResultChannel := make(chan stubs.ScreenshotResponse)
-					go makeScreenshotCall(client, pgmResultChannel)
-					generatePGM(p, c, (<-pgmResultChannel).World)
+					result, err := makeScreenshotCall(client, readyToDialResult.Compression, p.RPCTimeout)
+					if err != nil {
+						fmt.Println("Screenshot failed:", err)
+						break
+					}
+					generatePGM(p, c, result.World, result.CompletedTurns)
+				case 'c':
+					result, err := makeCensusCall(client, p.RPCTimeout)
+					if err != nil {
+						fmt.Println("Census failed:", err)
+						break
+					}
+					c.events <- CensusTaken{result.CompletedTurns, result.Census}
 				case 'q':
-					quitResultChannel := make(chan stubs.QuitResponse)
-					go makeQuitCall(client, quitResultChannel)
-					<-quitResultChannel
+					if err := quit(); err != nil {
+						cancel(err)
+					}
 					return
 				case 'k':
 					// send quit request
-					quitResultChannel := make(chan stubs.QuitResponse)
-					go makeQuitCall(client, quitResultChannel)
-					<-quitResultChannel
+					if err := quit(); err != nil {
+						cancel(err)
+						return
+					}
 
 					// wait for world to be read from Broker
 					wg.Wait()
 
 					// send close request
-					go makeCloseBrokerCall(client)
+					go func() {
+						if err := makeCloseBrokerCall(broker, p); err != nil {
+							fmt.Println("Close broker failed:", err)
+						}
+					}()
 					return
 				case 'p':
-					paused = !paused
-					if paused {
-						pauseResultChan := make(chan stubs.PauseResponse)
-						go makePauseCall(client, pauseResultChan)
+					if !paused {
+						result, err := makePauseCall(client, p.RPCTimeout)
+						if err != nil {
+							fmt.Println("Pause failed:", err)
+							break
+						}
+						paused = true
 						ticker.Stop()
-						c.events <- StateChange{(<-pauseResultChan).Turn, Paused}
+						c.events <- StateChange{result.Turn, Paused}
 					} else {
-						restartResultChan := make(chan stubs.RestartResponse)
-						go makeRestartCall(client, restartResultChan)
+						result, err := makeRestartCall(client, p.RPCTimeout)
+						if err != nil {
+							fmt.Println("Resume failed:", err)
+							break
+						}
+						paused = false
 						ticker.Reset(2 * time.Second)
-						c.events <- StateChange{(<-restartResultChan).Turn, Executing}
+						c.events <- StateChange{result.Turn, Executing}
 					}
 				}
+			case cells := <-p.Edits:
+				edit(cells)
+			case <-cancelRunGame:
+				return
 			}
 		}
 	}()
 
 	// get game result from broker
-	runGameResult := <-runGameResultChannel
+	result := <-runGameResultChannel
 	ticker.Stop()
 
 	// stop receiving world updates
 	close(stopListening)
+	<-listenerDone
+
+	if result.err != nil {
+		// there is no final world to report, so just let the user know we are done
+		fmt.Println("Game failed:", result.err)
+		cancelOnce.Do(func() { close(cancelRunGame) })
+		c.events <- StateChange{lastTurn, Quitting}
+		close(c.events)
+		return
+	}
+	runGameResult := result.res
 
 	// get final world and turns completed
 	finalWorld := runGameResult.World
@@ -334,7 +564,7 @@ func distributor(p Params, c distributorChannels) {
 	finalAliveCells := runGameResult.AliveCells
 
 	// generate pgm image of final world state
-	generatePGM(p, c, finalWorld)
+	generatePGM(p, c, finalWorld, finalCompletedTurns)
 
 	// Make sure that the Io has finished any output before exiting.
 	c.ioCommand <- ioCheckIdle
@@ -346,20 +576,45 @@ func distributor(p Params, c distributorChannels) {
 		CompletedTurns: finalCompletedTurns,
 		Alive:          finalAliveCells,
 	}
-      
+
 	// Close the channel to stop the SDL goroutine gracefully. Removing may cause deadlock.
 	close(c.events)
 
 }
 
-func generatePGM(p Params, c distributorChannels, world [][]byte) {
-	filename := fmt.Sprintf("%vx%vx%v", p.ImageWidth, p.ImageHeight, p.Turns)
+func generatePGM(p Params, c distributorChannels, world [][]byte, completedTurns int) {
+	ioMutex.Lock()
+	defer ioMutex.Unlock()
+
+	filename := outputName(p, completedTurns)
 	c.ioCommand <- ioOutput
 	c.ioFilename <- filename
 
-	for y := 0; y < p.ImageHeight; y++ {
-		for x := 0; x < p.ImageWidth; x++ {
-			c.ioOutput <- world[y][x]
-		}
+	c.ioOutput <- world
+
+	// a failed save is reported, but the game carries on
+	if err := <-c.ioResult; err != nil {
+		c.events <- IOFailed{completedTurns, filepath.Join(p.OutputDir, filename+".pgm"), err}
+		return
+	}
+	c.events <- ImageOutputComplete{completedTurns, filepath.Join(p.OutputDir, filename+".pgm")}
+}
+
+// outputName fills in the p.OutputName template for a world output after completedTurns.
+func outputName(p Params, completedTurns int) string {
+	input := fmt.Sprintf("%vx%v", p.ImageWidth, p.ImageHeight)
+	if p.Input != "" {
+		input = strings.TrimSuffix(filepath.Base(p.Input), filepath.Ext(p.Input))
+	}
+	seed := ""
+	if p.Soup != nil {
+		seed = strconv.FormatInt(p.Soup.Seed, 10)
 	}
+	return strings.NewReplacer(
+		"{width}", strconv.Itoa(p.ImageWidth),
+		"{height}", strconv.Itoa(p.ImageHeight),
+		"{turns}", strconv.Itoa(completedTurns),
+		"{input}", input,
+		"{seed}", seed,
+	).Replace(p.OutputName)
 }
@@ -0,0 +1,346 @@
+package gol
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestSendFlips checks that the flips of a turn are sent as one CellsFlipped event when batched,
+// and as a CellFlipped event for each cell otherwise.
+func TestSendFlips(t *testing.T) {
+	cells := []util.Cell{{X: 1, Y: 2}, {X: 3, Y: 4}}
+
+	events := make(chan Event, 10)
+	sendFlips(Params{BatchFlips: true}, events, 5, cells)
+	sendFlips(Params{BatchFlips: true}, events, 6, nil)
+	close(events)
+	var batched []Event
+	for event := range events {
+		batched = append(batched, event)
+	}
+	if expected := []Event{CellsFlipped{5, cells}}; !reflect.DeepEqual(batched, expected) {
+		t.Errorf("expected %v, got %#v", expected, batched)
+	}
+
+	events = make(chan Event, 10)
+	sendFlips(Params{}, events, 5, cells)
+	close(events)
+	var legacy []Event
+	for event := range events {
+		legacy = append(legacy, event)
+	}
+	if expected := []Event{CellFlipped{5, cells[0]}, CellFlipped{5, cells[1]}}; !reflect.DeepEqual(legacy, expected) {
+		t.Errorf("expected %v, got %#v", expected, legacy)
+	}
+}
+
+// fakeBroker stands in for the broker, returning the world it was sent as a screenshot after 3 turns and as the
+// final world after 5, once the game has been quit. The 5 turns are only sent to the controller then, just
+// before RunGame returns, as the last turns of a game are.
+type fakeBroker struct {
+	mutex    sync.Mutex
+	world    [][]byte
+	port     string        // port the controller is listening on
+	started  chan struct{} // closed once RunGame has been called
+	quit     chan struct{} // closed once Quit has been called
+	finished chan struct{} // closed once the turns have been sent, as RunGame returns
+	fail     error         // returned by RunGame in place of the final world, if not nil
+	edits    [][]util.Cell // cells it was asked to edit before the game was quit
+}
+
+func (b *fakeBroker) ReadyToDial(req stubs.ReadyToDialRequest, res *stubs.ReadyToDialResponse) (err error) {
+	b.mutex.Lock()
+	b.port = req.Port
+	b.mutex.Unlock()
+	return
+}
+
+func (b *fakeBroker) RunGame(req stubs.RunGameRequest, res *stubs.RunGameResponse) (err error) {
+	b.mutex.Lock()
+	b.world = req.World
+	close(b.started)
+	b.mutex.Unlock()
+	<-b.quit
+
+	b.mutex.Lock()
+	port := b.port
+	b.mutex.Unlock()
+	controller, err := stubs.Security{}.Dial(net.JoinHostPort("127.0.0.1", port), stubs.RoleBroker)
+	if err != nil {
+		return err
+	}
+	defer controller.Close()
+	for turn := 1; turn <= 5; turn++ {
+		state := stubs.SendWorldStateRequest{CompletedTurns: turn}
+		if err = controller.Call(stubs.SendWorldState, state, new(stubs.SendWorldStateResponse)); err != nil {
+			return err
+		}
+	}
+	close(b.finished)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.fail != nil {
+		return b.fail
+	}
+	res.World = req.World
+	res.CompletedTurns = 5
+	return
+}
+
+func (b *fakeBroker) AliveCellsCount(req stubs.AliveCellsCountRequest, res *stubs.AliveCellsCountResponse) (err error) {
+	return
+}
+
+func (b *fakeBroker) Screenshot(req stubs.ScreenshotRequest, res *stubs.ScreenshotResponse) (err error) {
+	b.mutex.Lock()
+	res.World = b.world
+	b.mutex.Unlock()
+	res.CompletedTurns = 3
+	return
+}
+
+func (b *fakeBroker) EditCells(req stubs.EditCellsRequest, res *stubs.EditCellsResponse) (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	select {
+	case <-b.quit:
+	default:
+		b.edits = append(b.edits, req.Cells)
+	}
+	return
+}
+
+func (b *fakeBroker) Quit(req stubs.QuitRequest, res *stubs.QuitResponse) (err error) {
+	close(b.quit)
+	return
+}
+
+func (b *fakeBroker) CloseBroker(req stubs.CloseBrokerRequest, res *stubs.CloseBrokerResponse) (err error) {
+	return
+}
+
+var (
+	testBroker         = new(fakeBroker)
+	registerTestBroker sync.Once
+)
+
+// runWithKeys runs a game of the 16x16 image against a fake broker, pressing keys once it has started,
+// and returns the events it sent.
+func runWithKeys(t *testing.T, p Params, keys ...rune) []Event {
+	events := make(chan Event, 1000)
+	startWithKeys(t, p, events, keys...)
+	var received []Event
+	for event := range events {
+		received = append(received, event)
+	}
+	return received
+}
+
+// startWithKeys starts a game of the 16x16 image against a fake broker sending events down events,
+// and presses keys in the background once it has started.
+func startWithKeys(t *testing.T, p Params, events chan<- Event, keys ...rune) {
+	registerTestBroker.Do(func() {
+		if err := rpc.RegisterName("Broker", testBroker); err != nil {
+			t.Fatal(err)
+		}
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go stubs.Security{}.Accept(listener)
+	brokerAddr = listener.Addr().String()
+	t.Cleanup(func() { brokerAddr = "127.0.0.1:8030" })
+
+	testBroker.mutex.Lock()
+	testBroker.started = make(chan struct{})
+	testBroker.quit = make(chan struct{})
+	testBroker.finished = make(chan struct{})
+	testBroker.edits = nil
+	started := testBroker.started
+	testBroker.mutex.Unlock()
+
+	p.Turns, p.Threads, p.ImageWidth, p.ImageHeight = 100, 1, 16, 16
+	p.Input = filepath.Join("..", "images", "16x16.pgm")
+	keyPresses := make(chan rune, len(keys))
+	go Run(p, events, keyPresses)
+	go func() {
+		<-started
+		for _, key := range keys {
+			keyPresses <- key
+		}
+	}()
+}
+
+// saves returns the events reporting each file saved or not, without their errors.
+func saves(t *testing.T, events []Event) []Event {
+	var saves []Event
+	for _, event := range events {
+		switch e := event.(type) {
+		case ImageOutputComplete:
+			saves = append(saves, e)
+		case IOFailed:
+			if e.Err == nil {
+				t.Errorf("expected IOFailed to carry an error, got %#v", e)
+			}
+			e.Err = nil
+			saves = append(saves, e)
+		}
+	}
+	return saves
+}
+
+// TestSaveEvents checks that ImageOutputComplete is sent once each image saved by the s, q and k keys has been
+// written, with its path and turn, and after the recording is saved.
+func TestSaveEvents(t *testing.T) {
+	for _, keys := range []string{"sq", "k"} {
+		dir := t.TempDir()
+		saves := saves(t, runWithKeys(t, Params{OutputDir: dir, Record: &Recording{Format: "gif"}}, []rune(keys)...))
+
+		var expected []Event
+		if keys == "sq" {
+			expected = append(expected, ImageOutputComplete{3, filepath.Join(dir, "16x16x3.pgm")})
+		}
+		expected = append(expected,
+			ImageOutputComplete{5, filepath.Join(dir, "16x16x5.gif")},
+			ImageOutputComplete{5, filepath.Join(dir, "16x16x5.pgm")})
+		if !reflect.DeepEqual(saves, expected) {
+			t.Errorf("%v: expected %v, got %v", keys, expected, saves)
+		}
+		for _, save := range saves {
+			if _, err := os.Stat(save.(ImageOutputComplete).Filename); err != nil {
+				t.Errorf("%v: %v", keys, err)
+			}
+		}
+	}
+}
+
+// TestSaveFailed checks that IOFailed is sent in place of ImageOutputComplete for each file that cannot be written.
+func TestSaveFailed(t *testing.T) {
+	// the output directory cannot be made where there is already a file
+	dir := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(dir, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	saves := saves(t, runWithKeys(t, Params{OutputDir: dir, Record: &Recording{Format: "gif"}}, 's', 'q'))
+
+	expected := []Event{
+		IOFailed{3, filepath.Join(dir, "16x16x3.pgm"), nil},
+		IOFailed{5, filepath.Join(dir, "16x16x5.gif"), nil},
+		IOFailed{5, filepath.Join(dir, "16x16x5.pgm"), nil},
+	}
+	if !reflect.DeepEqual(saves, expected) {
+		t.Errorf("expected %v, got %v", expected, saves)
+	}
+}
+
+// TestEditsBeforeKey checks that edits sent before a key are made before the key is acted on.
+func TestEditsBeforeKey(t *testing.T) {
+	cells := []util.Cell{{X: 1, Y: 2}}
+	edits := make(chan []util.Cell, 1)
+	edits <- cells
+	runWithKeys(t, Params{OutputDir: t.TempDir(), Edits: edits}, 'q')
+
+	testBroker.mutex.Lock()
+	defer testBroker.mutex.Unlock()
+	if expected := [][]util.Cell{cells}; !reflect.DeepEqual(testBroker.edits, expected) {
+		t.Errorf("expected %v to be edited before quitting, got %v", expected, testBroker.edits)
+	}
+}
+
+// TestLastTurns checks that the turns the broker sends just before the game ends are reported like any other,
+// before the final turn.
+func TestLastTurns(t *testing.T) {
+	// events are held up after the first turn until the game has ended, so that turns are left waiting
+	events := make(chan Event, 1)
+	startWithKeys(t, Params{OutputDir: t.TempDir()}, events, 'q')
+	testBroker.mutex.Lock()
+	finished := testBroker.finished
+	testBroker.mutex.Unlock()
+
+	var turns []int
+	for event := range events {
+		switch e := event.(type) {
+		case TurnComplete:
+			if len(turns) == 0 {
+				<-finished
+				time.Sleep(10 * time.Millisecond)
+			}
+			turns = append(turns, e.CompletedTurns)
+		case FinalTurnComplete:
+			if expected := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(turns, expected) {
+				t.Errorf("expected turns %v before the final turn, got %v", expected, turns)
+			}
+			return
+		}
+	}
+	t.Error("expected a FinalTurnComplete event")
+}
+
+// TestGameFailed checks that a game that fails is reported as quitting at the last turn it completed.
+func TestGameFailed(t *testing.T) {
+	testBroker.mutex.Lock()
+	testBroker.fail = errors.New("lost every server")
+	testBroker.mutex.Unlock()
+	t.Cleanup(func() {
+		testBroker.mutex.Lock()
+		testBroker.fail = nil
+		testBroker.mutex.Unlock()
+	})
+
+	events := runWithKeys(t, Params{OutputDir: t.TempDir()}, 'q')
+	last := events[len(events)-1]
+	if expected := (StateChange{5, Quitting}); last != expected {
+		t.Errorf("expected %v, got %v", expected, last)
+	}
+	for _, event := range events {
+		if _, ok := event.(FinalTurnComplete); ok {
+			t.Errorf("expected no final turn, got %v", event)
+		}
+	}
+}
+
+// TestBrokerUnreachable checks that a broker that cannot be dialled ends the game rather than the program.
+func TestBrokerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	brokerAddr = listener.Addr().String()
+	listener.Close()
+	t.Cleanup(func() { brokerAddr = "127.0.0.1:8030" })
+
+	events := make(chan Event, 1000)
+	Run(Params{Turns: 1, Threads: 1, ImageWidth: 16, ImageHeight: 16, Input: filepath.Join("..", "images", "16x16.pgm")}, events, nil)
+	var last Event
+	for event := range events {
+		last = event
+	}
+	if expected := (StateChange{0, Quitting}); last != expected {
+		t.Errorf("expected %v, got %v", expected, last)
+	}
+}
+
+// TestRecordingFailed checks that frames that cannot be saved are reported with the path of their directory.
+func TestRecordingFailed(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(dir, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	saves := saves(t, runWithKeys(t, Params{OutputDir: dir, Record: &Recording{Format: "pgm"}}, 'q'))
+	if expected := (IOFailed{0, filepath.Join(dir, "frames"), nil}); len(saves) == 0 || saves[0] != expected {
+		t.Errorf("expected %v first, got %v", expected, saves)
+	}
+}
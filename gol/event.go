@@ -2,6 +2,8 @@ package gol
 
 import (
 	"fmt"
+	"time"
+
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
@@ -23,11 +25,79 @@ type AliveCellsCount struct { // implements Event
 
 // ImageOutputComplete is an Event notifying the user about the completion of output.
 // This Event should be sent every time an image has been saved.
+// Filename is the path of the pgm file, or of a recorded gif, which is safe to read once this Event is sent.
 type ImageOutputComplete struct { // implements Event
 	CompletedTurns int
 	Filename       string
 }
 
+// IOFailed is an Event notifying the user that an image could not be read or saved.
+// If the input could not be read it is followed by Quitting, otherwise the game carries on.
+// Filename is the path of the file, as ImageOutputComplete would have reported it.
+type IOFailed struct { // implements Event
+	CompletedTurns int
+	Filename       string
+	Err            error
+}
+
+// WorkerJoined is an Event notifying the user that a server has been given a slice of the world to compute.
+// StartY is the first row of the slice and EndY the row after its last.
+type WorkerJoined struct { // implements Event
+	CompletedTurns int
+	Address        string
+	StartY, EndY   int
+}
+
+// WorkerLost is an Event notifying the user that a server failed to compute its slice, which stops the game.
+type WorkerLost struct { // implements Event
+	CompletedTurns int
+	Address        string
+	StartY, EndY   int
+	Reason         string
+}
+
+// TurnTiming is an Event notifying the user about where the time taken by a turn went.
+// This Event is sent after each turn when Params.ReportTiming is set.
+type TurnTiming struct { // implements Event
+	CompletedTurns int
+	Compute        time.Duration // spent by the slowest server computing its slice
+	Network        time.Duration // spent waiting for the servers other than computing
+	Assembly       time.Duration // spent by the broker putting the slices together
+	TurnsPerSecond float64       // from the time since the previous turn, including reporting it to the controller
+}
+
+// StabilityReached is an Event notifying the user that the world has started to repeat itself,
+// as the world after FirstTurn is the same as the world Period turns later. A still life has period 1.
+// This Event is sent once, on the turn the repeat is found, when Params.DetectPeriod is set.
+type StabilityReached struct { // implements Event
+	CompletedTurns int
+	Period         int
+	FirstTurn      int
+}
+
+// CensusTaken is an Event notifying the user of the objects in the world, such as blocks and gliders.
+// This Event is sent every time c is pressed.
+type CensusTaken struct { // implements Event
+	CompletedTurns int
+	Census         util.Census
+}
+
+// RegionsUpdated is an Event notifying the user of the population statistics of each tile of the world,
+// when it is divided into a grid of Params.RegionColumns x Params.RegionRows tiles.
+// This Event is sent every 2s along with AliveCellsCount.
+type RegionsUpdated struct { // implements Event
+	CompletedTurns int
+	Stats          util.RegionStats
+}
+
+// CellsEdited is an Event notifying the GUI about the cells toggled by the user while the game was paused,
+// which the broker has flipped in its world. SDL will render a frame when this event is sent.
+// This Event is sent before the game resumes, for each batch of edits sent down Params.Edits.
+type CellsEdited struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+}
+
 // State represents a change in the state of execution.
 type State int
 
@@ -52,9 +122,16 @@ type CellFlipped struct { // implements Event
 	Cell           util.Cell
 }
 
+// CellsFlipped is an Event notifying the GUI about every cell that changed state in a turn,
+// in place of a CellFlipped event for each of them when Params.BatchFlips is set.
+type CellsFlipped struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+}
+
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
-// All CellFlipped events must be sent *before* TurnComplete.
+// All CellFlipped and CellsFlipped events must be sent *before* TurnComplete.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
 }
@@ -106,6 +183,80 @@ func (event ImageOutputComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event IOFailed) String() string {
+	return fmt.Sprintf("File %v failed: %v", event.Filename, event.Err)
+}
+
+func (event IOFailed) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event WorkerJoined) String() string {
+	return fmt.Sprintf("Worker %v joined with rows %v-%v", event.Address, event.StartY, event.EndY-1)
+}
+
+func (event WorkerJoined) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event WorkerLost) String() string {
+	return fmt.Sprintf("Worker %v with rows %v-%v lost: %v", event.Address, event.StartY, event.EndY-1, event.Reason)
+}
+
+func (event WorkerLost) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event TurnTiming) String() string {
+	return fmt.Sprintf("Compute %v, network %v, assembly %v, %.1f turns/s",
+		event.Compute, event.Network, event.Assembly, event.TurnsPerSecond)
+}
+
+func (event TurnTiming) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event StabilityReached) String() string {
+	if event.Period == 1 {
+		return fmt.Sprintf("Still since turn %v", event.FirstTurn)
+	}
+	return fmt.Sprintf("Repeating every %v turns since turn %v", event.Period, event.FirstTurn)
+}
+
+func (event StabilityReached) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event CensusTaken) String() string {
+	return fmt.Sprintf("Census of %v objects: %v", event.Census.Total(), event.Census)
+}
+
+func (event CensusTaken) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event RegionsUpdated) String() string {
+	if len(event.Stats.Regions) == 0 || len(event.Stats.Regions[0]) == 0 {
+		return "No regions"
+	}
+	activeRow, activeColumn, aliveRow, aliveColumn := event.Stats.Busiest()
+	return fmt.Sprintf("Most active region (%v, %v) with %v births and deaths, most alive (%v, %v) with %v cells",
+		activeColumn, activeRow, event.Stats.Regions[activeRow][activeColumn].Activity,
+		aliveColumn, aliveRow, event.Stats.Regions[aliveRow][aliveColumn].Alive)
+}
+
+func (event RegionsUpdated) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event CellsEdited) String() string {
+	return fmt.Sprintf("Edited %v cells", len(event.Cells))
+}
+
+func (event CellsEdited) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event CellFlipped) String() string {
 	return fmt.Sprintf("")
 }
@@ -114,6 +265,14 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CellsFlipped) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -0,0 +1,413 @@
+package gol
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// An event log holds every Event of a run, so that it can be replayed without a broker or servers.
+// After a header giving the size of the world, the log is gzip compressed and each event is written as
+// its type, its completed turns as a uvarint, then what else it holds:
+//
+//	AliveCellsCount      count
+//	ImageOutputComplete  filename
+//	IOFailed             filename, error
+//	StateChange          state
+//	CellFlipped          x, y
+//	CellsFlipped         number of cells, then x, y for each
+//	TurnComplete         milliseconds since the log was started, to replay the run at its own speed
+//	FinalTurnComplete    number of alive cells, then x, y for each
+//	WorkerJoined         address, first row, row after the last
+//	WorkerLost           address, first row, row after the last, reason
+//	TurnTiming           compute, network and assembly nanoseconds, then the bits of turns per second
+//	StabilityReached     period, first turn
+//	CensusTaken          number of kinds of object, then the name and count of each
+//	RegionsUpdated       width, height, columns, rows, then alive, births, deaths and activity of each tile by row
+//	CellsEdited          number of cells, then x, y for each
+//
+// Numbers are uvarints and strings are a uvarint length followed by the bytes.
+
+const eventLogMagic = "GOLEVENTS1\n"
+
+const (
+	logAliveCellsCount byte = iota
+	logImageOutputComplete
+	logIOFailed
+	logStateChange
+	logCellFlipped
+	logTurnComplete
+	logFinalTurnComplete
+	logCellsFlipped
+	logWorkerJoined
+	logWorkerLost
+	logTurnTiming
+	logStabilityReached
+	logCensusTaken
+	logRegionsUpdated
+	logCellsEdited
+)
+
+// EventWriter writes events to an event log.
+type EventWriter struct {
+	gz    *gzip.Writer
+	out   *bufio.Writer
+	start time.Time
+	buf   [binary.MaxVarintLen64]byte
+}
+
+// NewEventWriter starts an event log for a world of the given size.
+func NewEventWriter(w io.Writer, width, height int) (*EventWriter, error) {
+	if _, err := fmt.Fprintf(w, "%v%v %v\n", eventLogMagic, width, height); err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(w)
+	return &EventWriter{gz: gz, out: bufio.NewWriter(gz), start: time.Now()}, nil
+}
+
+func (ew *EventWriter) uint(n int) {
+	ew.out.Write(ew.buf[:binary.PutUvarint(ew.buf[:], uint64(n))])
+}
+
+func (ew *EventWriter) float(f float64) {
+	ew.out.Write(ew.buf[:binary.PutUvarint(ew.buf[:], math.Float64bits(f))])
+}
+
+func (ew *EventWriter) cells(cells []util.Cell) {
+	ew.uint(len(cells))
+	for _, c := range cells {
+		ew.uint(c.X)
+		ew.uint(c.Y)
+	}
+}
+
+func (ew *EventWriter) string(s string) {
+	ew.uint(len(s))
+	ew.out.WriteString(s)
+}
+
+// Write adds an event to the log. Events of types that a log cannot hold are refused.
+func (ew *EventWriter) Write(event Event) error {
+	switch e := event.(type) {
+	case AliveCellsCount:
+		ew.out.WriteByte(logAliveCellsCount)
+		ew.uint(e.CompletedTurns)
+		ew.uint(e.CellsCount)
+	case ImageOutputComplete:
+		ew.out.WriteByte(logImageOutputComplete)
+		ew.uint(e.CompletedTurns)
+		ew.string(e.Filename)
+	case IOFailed:
+		ew.out.WriteByte(logIOFailed)
+		ew.uint(e.CompletedTurns)
+		ew.string(e.Filename)
+		ew.string(e.Err.Error())
+	case StateChange:
+		ew.out.WriteByte(logStateChange)
+		ew.uint(e.CompletedTurns)
+		ew.uint(int(e.NewState))
+	case CellFlipped:
+		ew.out.WriteByte(logCellFlipped)
+		ew.uint(e.CompletedTurns)
+		ew.uint(e.Cell.X)
+		ew.uint(e.Cell.Y)
+	case CellsFlipped:
+		ew.out.WriteByte(logCellsFlipped)
+		ew.uint(e.CompletedTurns)
+		ew.cells(e.Cells)
+	case TurnComplete:
+		ew.out.WriteByte(logTurnComplete)
+		ew.uint(e.CompletedTurns)
+		ew.uint(int(time.Since(ew.start) / time.Millisecond))
+	case FinalTurnComplete:
+		ew.out.WriteByte(logFinalTurnComplete)
+		ew.uint(e.CompletedTurns)
+		ew.cells(e.Alive)
+	case WorkerJoined:
+		ew.out.WriteByte(logWorkerJoined)
+		ew.uint(e.CompletedTurns)
+		ew.string(e.Address)
+		ew.uint(e.StartY)
+		ew.uint(e.EndY)
+	case WorkerLost:
+		ew.out.WriteByte(logWorkerLost)
+		ew.uint(e.CompletedTurns)
+		ew.string(e.Address)
+		ew.uint(e.StartY)
+		ew.uint(e.EndY)
+		ew.string(e.Reason)
+	case TurnTiming:
+		ew.out.WriteByte(logTurnTiming)
+		ew.uint(e.CompletedTurns)
+		ew.uint(int(e.Compute))
+		ew.uint(int(e.Network))
+		ew.uint(int(e.Assembly))
+		ew.float(e.TurnsPerSecond)
+	case StabilityReached:
+		ew.out.WriteByte(logStabilityReached)
+		ew.uint(e.CompletedTurns)
+		ew.uint(e.Period)
+		ew.uint(e.FirstTurn)
+	case CensusTaken:
+		ew.out.WriteByte(logCensusTaken)
+		ew.uint(e.CompletedTurns)
+		ew.uint(len(e.Census))
+		for _, entry := range e.Census.Entries() {
+			ew.string(entry.Name)
+			ew.uint(entry.Count)
+		}
+	case RegionsUpdated:
+		ew.out.WriteByte(logRegionsUpdated)
+		ew.uint(e.CompletedTurns)
+		ew.uint(e.Stats.Width)
+		ew.uint(e.Stats.Height)
+		ew.uint(e.Stats.Columns)
+		ew.uint(e.Stats.Rows)
+		for _, row := range e.Stats.Regions {
+			for _, region := range row {
+				ew.uint(region.Alive)
+				ew.uint(region.Births)
+				ew.uint(region.Deaths)
+				ew.uint(region.Activity)
+			}
+		}
+	case CellsEdited:
+		ew.out.WriteByte(logCellsEdited)
+		ew.uint(e.CompletedTurns)
+		ew.cells(e.Cells)
+	default:
+		return fmt.Errorf("cannot log %T events", event)
+	}
+	return nil
+}
+
+// Close finishes the log, but does not close the underlying writer.
+func (ew *EventWriter) Close() error {
+	if err := ew.out.Flush(); err != nil {
+		return err
+	}
+	return ew.gz.Close()
+}
+
+// EventReader reads events back from an event log.
+type EventReader struct {
+	Width, Height int
+	in            *bufio.Reader
+}
+
+// NewEventReader reads the header of an event log.
+func NewEventReader(r io.Reader) (*EventReader, error) {
+	header := bufio.NewReader(r)
+	magic := make([]byte, len(eventLogMagic))
+	if _, err := io.ReadFull(header, magic); err != nil || string(magic) != eventLogMagic {
+		return nil, errors.New("not an event log")
+	}
+	er := new(EventReader)
+	if _, err := fmt.Fscanf(header, "%d %d\n", &er.Width, &er.Height); err != nil {
+		return nil, fmt.Errorf("event log header: %w", err)
+	}
+	gz, err := gzip.NewReader(header)
+	if err != nil {
+		return nil, err
+	}
+	er.in = bufio.NewReader(gz)
+	return er, nil
+}
+
+// Read returns the next event, and for a TurnComplete how long after the start of the log it was sent.
+// It returns io.EOF at the end of the log.
+func (er *EventReader) Read() (Event, time.Duration, error) {
+	kind, err := er.in.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	// after the first error these return zero values, and the error is returned at the end
+	readUint := func() int {
+		n, e := binary.ReadUvarint(er.in)
+		if err == nil {
+			err = e
+		}
+		return int(n)
+	}
+	readFloat := func() float64 {
+		bits, e := binary.ReadUvarint(er.in)
+		if err == nil {
+			err = e
+		}
+		return math.Float64frombits(bits)
+	}
+	readString := func() string {
+		n := readUint()
+		if err != nil {
+			return ""
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(er.in, b)
+		return string(b)
+	}
+
+	// a list of cells can be no longer than the world has cells
+	readCells := func() []util.Cell {
+		n := readUint()
+		if err == nil && n > er.Width*er.Height {
+			err = fmt.Errorf("event log has %v cells in a %vx%v world", n, er.Width, er.Height)
+		}
+		if err != nil {
+			return nil
+		}
+		cells := make([]util.Cell, n)
+		for i := range cells {
+			cells[i] = util.Cell{X: readUint(), Y: readUint()}
+		}
+		return cells
+	}
+
+	turns := readUint()
+	var event Event
+	var elapsed time.Duration
+	switch kind {
+	case logAliveCellsCount:
+		event = AliveCellsCount{turns, readUint()}
+	case logImageOutputComplete:
+		event = ImageOutputComplete{turns, readString()}
+	case logIOFailed:
+		filename := readString()
+		event = IOFailed{turns, filename, errors.New(readString())}
+	case logStateChange:
+		event = StateChange{turns, State(readUint())}
+	case logCellFlipped:
+		event = CellFlipped{turns, util.Cell{X: readUint(), Y: readUint()}}
+	case logCellsFlipped:
+		event = CellsFlipped{turns, readCells()}
+	case logTurnComplete:
+		elapsed = time.Duration(readUint()) * time.Millisecond
+		event = TurnComplete{turns}
+	case logFinalTurnComplete:
+		event = FinalTurnComplete{turns, readCells()}
+	case logWorkerJoined:
+		address := readString()
+		event = WorkerJoined{turns, address, readUint(), readUint()}
+	case logWorkerLost:
+		address := readString()
+		startY, endY := readUint(), readUint()
+		event = WorkerLost{turns, address, startY, endY, readString()}
+	case logTurnTiming:
+		compute, network, assembly := readUint(), readUint(), readUint()
+		event = TurnTiming{turns, time.Duration(compute), time.Duration(network), time.Duration(assembly), readFloat()}
+	case logStabilityReached:
+		event = StabilityReached{turns, readUint(), readUint()}
+	case logCensusTaken:
+		census := make(util.Census)
+		for n := readUint(); n > 0 && err == nil; n-- {
+			name := readString()
+			census[name] = readUint()
+		}
+		event = CensusTaken{turns, census}
+	case logRegionsUpdated:
+		stats := util.RegionStats{Width: readUint(), Height: readUint(), Columns: readUint(), Rows: readUint()}
+		if err == nil && stats.Columns*stats.Rows > er.Width*er.Height {
+			err = fmt.Errorf("event log has %vx%v regions in a %vx%v world", stats.Columns, stats.Rows, er.Width, er.Height)
+		}
+		for r := 0; r < stats.Rows && err == nil; r++ {
+			row := make([]util.Region, stats.Columns)
+			for i := range row {
+				row[i] = util.Region{Alive: readUint(), Births: readUint(), Deaths: readUint(), Activity: readUint()}
+			}
+			stats.Regions = append(stats.Regions, row)
+		}
+		event = RegionsUpdated{turns, stats}
+	case logCellsEdited:
+		event = CellsEdited{turns, readCells()}
+	default:
+		return nil, 0, fmt.Errorf("unknown event type %v in event log", kind)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return event, elapsed, err
+}
+
+// LogEvents writes each event from events to the log until it is closed, then finishes the log.
+// If the log cannot be written to, events are still taken so as not to hold up the game,
+// and the first error is returned at the end.
+func LogEvents(ew *EventWriter, events <-chan Event) error {
+	var err error
+	for event := range events {
+		if err == nil {
+			err = ew.Write(event)
+		}
+	}
+	if closeErr := ew.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Replay sends the events in a log to events at speed times the speed they were sent, or as fast as they are taken
+// if speed is 0, and closes events at the end. Pressing p pauses and resumes the replay, and q stops it.
+func Replay(er *EventReader, events chan<- Event, keyPresses <-chan rune, speed float64) error {
+	defer close(events)
+	start := time.Now()
+
+	// handleKey reports whether the replay should stop, and while it is paused, waits for it to be resumed
+	handleKey := func(key rune, turns int) bool {
+		switch key {
+		case 'q':
+			return true
+		case 'p':
+			pausedAt := time.Now()
+			events <- StateChange{turns, Paused}
+			for key = <-keyPresses; key != 'p'; key = <-keyPresses {
+				if key == 'q' {
+					return true
+				}
+			}
+			events <- StateChange{turns, Executing}
+			// carry on from where the replay was paused
+			start = start.Add(time.Since(pausedAt))
+		}
+		return false
+	}
+
+	for {
+		event, elapsed, err := er.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		turns := event.GetCompletedTurns()
+
+		if _, ok := event.(TurnComplete); ok && speed > 0 {
+			due := func() time.Duration { return time.Until(start.Add(time.Duration(float64(elapsed) / speed))) }
+			for wait := due(); wait > 0; wait = due() {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case key := <-keyPresses:
+					timer.Stop()
+					if handleKey(key, turns) {
+						return nil
+					}
+				}
+			}
+		} else {
+			select {
+			case key := <-keyPresses:
+				if handleKey(key, turns) {
+					return nil
+				}
+			default:
+			}
+		}
+		events <- event
+	}
+}
@@ -0,0 +1,141 @@
+package gol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// loggedEvents has one of every type of event.
+var loggedEvents = []Event{
+	CellFlipped{0, util.Cell{X: 3, Y: 300}},
+	WorkerJoined{0, "127.0.0.1:8050", 0, 128},
+	StateChange{0, Executing},
+	CellFlipped{1, util.Cell{X: 4, Y: 0}},
+	CellsFlipped{1, []util.Cell{{X: 5, Y: 0}, {X: 6, Y: 511}}},
+	CellsEdited{1, []util.Cell{{X: 7, Y: 8}}},
+	TurnComplete{1},
+	TurnTiming{1, 3 * time.Millisecond, 2 * time.Millisecond, time.Millisecond, 142.5},
+	AliveCellsCount{1, 1000},
+	StabilityReached{1, 2, 0},
+	CensusTaken{1, util.Census{"block": 3, "other (5 cells)": 1}},
+	RegionsUpdated{1, util.RegionStats{Width: 512, Height: 512, Columns: 2, Rows: 1, Regions: [][]util.Region{{
+		{Alive: 1, Births: 2, Deaths: 3, Activity: 4}, {Alive: 5, Births: 6, Deaths: 7, Activity: 8},
+	}}}},
+	ImageOutputComplete{1, "out/512x512x1.pgm"},
+	IOFailed{1, "512x512x1", errors.New("disk full")},
+	WorkerLost{1, "127.0.0.1:8051", 128, 256, "connection refused"},
+	StateChange{1, Quitting},
+	FinalTurnComplete{1, []util.Cell{{X: 1, Y: 2}, {X: 511, Y: 511}}},
+}
+
+func writeEventLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	writer, err := NewEventWriter(&buf, 512, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range loggedEvents {
+		if err := writer.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// TestEventLog checks that every type of event reads back as it was written.
+func TestEventLog(t *testing.T) {
+	reader, err := NewEventReader(writeEventLog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Width != 512 || reader.Height != 512 {
+		t.Errorf("expected a 512x512 world, got %vx%v", reader.Width, reader.Height)
+	}
+	for _, expected := range loggedEvents {
+		event, _, err := reader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failed, ok := expected.(IOFailed); ok {
+			// errors are logged as their message
+			if got, ok := event.(IOFailed); !ok || got.Filename != failed.Filename || got.Err.Error() != failed.Err.Error() {
+				t.Errorf("expected %#v, got %#v", expected, event)
+			}
+		} else if !reflect.DeepEqual(event, expected) {
+			t.Errorf("expected %#v, got %#v", expected, event)
+		}
+	}
+	if _, _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected the end of the log, got %v", err)
+	}
+
+	if _, err := NewEventReader(bytes.NewReader([]byte("P5\n512 512\n255\n"))); err == nil {
+		t.Error("expected an error reading something other than an event log")
+	}
+}
+
+// TestReplay checks that a replay sends every event, and stops early when q is pressed.
+func TestReplay(t *testing.T) {
+	reader, err := NewEventReader(writeEventLog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Event, len(loggedEvents))
+	if err := Replay(reader, events, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for range events {
+		count++
+	}
+	if count != len(loggedEvents) {
+		t.Errorf("expected %v events, got %v", len(loggedEvents), count)
+	}
+
+	reader, _ = NewEventReader(writeEventLog(t))
+	events = make(chan Event, len(loggedEvents))
+	keyPresses := make(chan rune, 1)
+	keyPresses <- 'q'
+	if err := Replay(reader, events, keyPresses, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected no events after q was pressed")
+	}
+}
+
+// TestEmptyRegionsUpdated checks that a RegionsUpdated event with no tiles, as a log can hold,
+// reads back and can be printed.
+func TestEmptyRegionsUpdated(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewEventWriter(&buf, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(RegionsUpdated{3, util.RegionStats{Width: 16, Height: 16}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewEventReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, _, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := event.String(); s != "No regions" {
+		t.Errorf("expected %q, got %q", "No regions", s)
+	}
+}
@@ -1,21 +1,90 @@
 package gol
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// DefaultRPCTimeout is used in place of a zero Params.RPCTimeout.
+const DefaultRPCTimeout = 10 * time.Second
+
+// DefaultImageSize is the width and height of the world when there is no input file to take them from.
+const DefaultImageSize = 512
+
+// DefaultOutputName is the template used in place of an empty Params.OutputName.
+// {width}, {height} and {turns} are replaced by the size of the world and the turns completed when it was output,
+// {input} by the name of the input file without its extension, and {seed} by the seed of a soup.
+// The seed is added to the end of a template without one, so that soups can always be reproduced.
+const DefaultOutputName = "{width}x{height}x{turns}"
+
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
-	Turns       int
-	Threads     int
-	ImageWidth  int
-	ImageHeight int
+	Turns         int
+	Threads       int
+	ImageWidth    int
+	ImageHeight   int
+	RPCTimeout    time.Duration // deadline for each call to the broker, negative disables it
+	Security      stubs.Security
+	Compression   string     // world encoding to offer the broker, such as stubs.Flate, "" for none
+	Input         string     // file to load instead of images/WxH.pgm, either a .pgm, or a .png or pattern to centre in the world
+	Soup          *Soup      // random world to start from in place of the input, nil for none
+	Placements    Placements // patterns to place in the input world, or in a dead world if there is no input
+	OutputDir     string     // directory that output files are written to, "" for out
+	OutputName    string     // template for the names of output files, "" for DefaultOutputName
+	Record        *Recording // turns to save as an animation or frame sequence, nil for none
+	OutputRle     bool       // whether to write an rle file next to each pgm file
+	OutputLife106 bool       // whether to write a Life 1.06 (.lif) file next to each pgm file
+	OutputPng     bool       // whether to write a png file next to each pgm file
+	OutputCensus  bool       // whether to write a census of the objects in the world next to each pgm file
+	PngScale      int        // width in pixels of each cell in png output, 0 for 1
+	PngThreshold  int        // brightness at or above which a pixel of a png input is alive, 0 for DefaultPngThreshold
+	BatchFlips    bool       // whether to send one CellsFlipped event per turn in place of a CellFlipped event per cell, set by main unless -legacyFlips is given
+	ReportTiming  bool       // whether to send a TurnTiming event after each turn
+	DetectPeriod  int        // longest period of oscillation for the broker to look for, 0 to not look
+	SkipCycles    bool       // whether to jump to the last turn once the world is found to repeat
+	RegionColumns int        // size of the grid of tiles to send RegionsUpdated events for, 0 for none
+	RegionRows    int
+	Edits         <-chan []util.Cell // batches of cells toggled while paused, each made before the next key press, nil for none
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	if p.RPCTimeout == 0 {
+		p.RPCTimeout = DefaultRPCTimeout
+	}
+	if p.PngScale <= 0 {
+		p.PngScale = 1
+	}
+	if p.PngThreshold == 0 {
+		p.PngThreshold = DefaultPngThreshold
+	}
+	if p.OutputDir == "" {
+		p.OutputDir = "out"
+	}
+	if p.OutputName == "" {
+		p.OutputName = DefaultOutputName
+	}
+	if p.Soup != nil {
+		soup := p.Soup.withDefaults(p.ImageWidth, p.ImageHeight)
+		if soup.Seed == 0 {
+			soup.Seed = time.Now().UnixNano()
+		}
+		p.Soup = &soup
+		if !strings.Contains(p.OutputName, "{seed}") {
+			p.OutputName += "-seed{seed}"
+		}
+	}
 
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	ioFilename := make(chan string)
-	ioOutput := make(chan uint8, 10000000)
-	ioInput := make(chan uint8)
+	ioOutput := make(chan [][]byte)
+	ioInput := make(chan [][]byte)
+	ioResult := make(chan error)
 
 	ioChannels := ioChannels{
 		command:  ioCommand,
@@ -23,6 +92,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		filename: ioFilename,
 		output:   ioOutput,
 		input:    ioInput,
+		result:   ioResult,
 	}
 	go startIo(p, ioChannels)
 
@@ -33,7 +103,27 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		ioFilename: ioFilename,
 		ioOutput:   ioOutput,
 		ioInput:    ioInput,
+		ioResult:   ioResult,
 		keyPresses: keyPresses,
 	}
 	distributor(p, distributorChannels)
 }
+
+// InputSize returns the size of the world held by the input file, or DefaultImageSize if there is none.
+// It is used for whichever of ImageWidth and ImageHeight are 0.
+func InputSize(p Params) (width, height int, err error) {
+	if p.Input == "" {
+		return DefaultImageSize, DefaultImageSize, nil
+	}
+	if p.PngThreshold == 0 {
+		p.PngThreshold = DefaultPngThreshold
+	}
+	world, err := readWorld(p.Input, 0, 0, p.PngThreshold)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(world) == 0 {
+		return 0, 0, fmt.Errorf("%v: there are no cells to take the size of the world from", p.Input)
+	}
+	return len(world[0]), len(world), nil
+}
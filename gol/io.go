@@ -1,10 +1,10 @@
 package gol
 
 import (
+	"bufio"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"uk.ac.bris.cs/gameoflife/util"
 )
@@ -14,8 +14,9 @@ type ioChannels struct {
 	idle    chan<- bool
 
 	filename <-chan string
-	output   <-chan uint8
-	input    chan<- uint8
+	output   <-chan [][]byte
+	input    chan<- [][]byte
+	result   chan<- error
 }
 
 // ioState is the internal ioState of the io goroutine.
@@ -38,91 +39,229 @@ const (
 	ioCheckIdle
 )
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
+// writePgmImage receives a world and writes it to a pgm file, along with any other output formats,
+// then reports whether that worked to the distributor.
+// The distributor must not change the world until it has been sent the result.
 func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
-
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
+	world := <-io.channels.output
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
+	io.channels.result <- io.writeImages(filename, world)
+}
 
-	_, _ = file.WriteString("P5\n")
-	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
-
-	world := make([][]byte, io.params.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
-	}
-
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			val := <-io.channels.output
-			//if val != 0 {
-			//	fmt.Println(x, y)
-			//}
-			world[y][x] = val
-		}
+// writeImages writes world to a pgm file, and to any other output formats that have been asked for.
+func (io *ioState) writeImages(filename string, world [][]byte) error {
+	ioError := os.MkdirAll(io.params.OutputDir, os.ModePerm)
+	if ioError != nil {
+		return ioError
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			util.Check(ioError)
-		}
+	file, ioError := os.Create(filepath.Join(io.params.OutputDir, filename+".pgm"))
+	if ioError != nil {
+		return ioError
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	_, _ = fmt.Fprintln(out, "P5")
+	for _, comment := range io.metadata() {
+		_, _ = fmt.Fprintln(out, "#", comment)
+	}
+	_, _ = fmt.Fprintf(out, "%v %v\n255\n", io.params.ImageWidth, io.params.ImageHeight)
+	for _, row := range world {
+		_, _ = out.Write(row)
+	}
+	// a bufio.Writer keeps the first error, so checking Flush covers every write
+	ioError = out.Flush()
+	if ioError != nil {
+		return ioError
 	}
 
 	ioError = file.Sync()
-	util.Check(ioError)
+	if ioError != nil {
+		return ioError
+	}
 
 	fmt.Println("File", filename, "output done!")
+
+	if io.params.OutputRle {
+		if ioError = io.writeRleImage(filename, world); ioError != nil {
+			return ioError
+		}
+	}
+	if io.params.OutputLife106 {
+		if ioError = io.writeLife106Image(filename, world); ioError != nil {
+			return ioError
+		}
+	}
+	if io.params.OutputPng {
+		if ioError = io.writePngImage(filename, world); ioError != nil {
+			return ioError
+		}
+	}
+	if io.params.OutputCensus {
+		if ioError = io.writeCensus(filename, world); ioError != nil {
+			return ioError
+		}
+	}
+	return nil
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func (io *ioState) readPgmImage() {
+// metadata returns the comments written to output files, which record how to reproduce the world.
+func (io *ioState) metadata() []string {
+	if io.params.Soup == nil {
+		return nil
+	}
+	return []string{io.params.Soup.String()}
+}
 
-	// Request a filename from the distributor.
-	filename := <-io.channels.filename
+// writeRleImage writes world to an rle file next to the pgm file of the same name.
+func (io *ioState) writeRleImage(filename string, world [][]byte) error {
+	file, ioError := os.Create(filepath.Join(io.params.OutputDir, filename+".rle"))
+	if ioError != nil {
+		return ioError
+	}
+	defer file.Close()
 
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
+	if ioError = writeRle(file, world, io.metadata()...); ioError != nil {
+		return ioError
+	}
 
-	fields := strings.Fields(string(data))
+	fmt.Println("File", filename+".rle", "output done!")
+	return nil
+}
 
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+// writeLife106Image writes the alive cells of world to a Life 1.06 file next to the pgm file of the same name.
+func (io *ioState) writeLife106Image(filename string, world [][]byte) error {
+	file, ioError := os.Create(filepath.Join(io.params.OutputDir, filename+".lif"))
+	if ioError != nil {
+		return ioError
 	}
+	defer file.Close()
 
-	width, _ := strconv.Atoi(fields[1])
-	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+	var alive []util.Cell
+	for y, row := range world {
+		for x, b := range row {
+			if b != 0 {
+				alive = append(alive, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	if ioError = writeLife106(file, alive, io.metadata()...); ioError != nil {
+		return ioError
 	}
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+	fmt.Println("File", filename+".lif", "output done!")
+	return nil
+}
+
+// writePngImage writes world to a png file next to the pgm file of the same name, scaled up by PngScale.
+func (io *ioState) writePngImage(filename string, world [][]byte) error {
+	file, ioError := os.Create(filepath.Join(io.params.OutputDir, filename+".png"))
+	if ioError != nil {
+		return ioError
 	}
+	defer file.Close()
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+	if ioError = writePng(file, world, io.params.PngScale); ioError != nil {
+		return ioError
 	}
 
-	image := []byte(fields[4])
+	fmt.Println("File", filename+".png", "output done!")
+	return nil
+}
 
-	for _, b := range image {
-		io.channels.input <- b
+// writeCensus writes a census of the objects in world to a text file next to the pgm file of the same name,
+// with a line for each kind of object giving how many there are.
+func (io *ioState) writeCensus(filename string, world [][]byte) error {
+	file, ioError := os.Create(filepath.Join(io.params.OutputDir, filename+"-census.txt"))
+	if ioError != nil {
+		return ioError
 	}
+	defer file.Close()
 
-	fmt.Println("File", filename, "input done!")
+	census := util.TakeCensus(world)
+	out := bufio.NewWriter(file)
+	for _, comment := range io.metadata() {
+		_, _ = fmt.Fprintln(out, "#", comment)
+	}
+	_, _ = fmt.Fprintf(out, "# %v objects in %v\n", census.Total(), filename)
+	for _, e := range census.Entries() {
+		_, _ = fmt.Fprintf(out, "%v\t%v\n", e.Count, e.Name)
+	}
+	if ioError = out.Flush(); ioError != nil {
+		return ioError
+	}
+
+	fmt.Println("File", filename+"-census.txt", "output done!")
+	return nil
+}
+
+// readImage generates a soup, or opens the input file, or starts from a dead world if filename is empty,
+// and places any patterns in it.
+// It then reports whether that worked to the distributor, and if it did, sends it the world.
+func (io *ioState) readImage(filename string) {
+	var world [][]byte
+	var ioError error
+	if io.params.Soup != nil {
+		world, ioError = generateSoup(*io.params.Soup, io.params.ImageWidth, io.params.ImageHeight)
+	} else if filename != "" {
+		world, ioError = readWorld(filename, io.params.ImageWidth, io.params.ImageHeight, io.params.PngThreshold)
+	} else {
+		world = makeWorld(io.params.ImageWidth, io.params.ImageHeight)
+	}
+	if ioError == nil {
+		ioError = place(world, io.params.Placements, io.params.PngThreshold)
+	}
+	io.channels.result <- ioError
+	if ioError != nil {
+		return
+	}
+
+	io.channels.input <- world
+
+	if io.params.Soup != nil {
+		fmt.Println("Generated", io.params.Soup)
+	} else if filename != "" {
+		fmt.Println("File", filepath.Base(filename), "input done!")
+	}
+	for _, pl := range io.params.Placements {
+		fmt.Println("File", filepath.Base(pl.Pattern), "placed at", pl.X, pl.Y)
+	}
+}
+
+// readWorld reads the world held by an image or pattern file, in the format given by its extension.
+// Png images and patterns are centred in a world of the given size, which must match the size of a pgm or pbm image.
+// If width and height are 0 the world is the size of the image or pattern.
+func readWorld(filename string, width, height, threshold int) ([][]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var world [][]byte
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".pgm", ".pbm", ".pnm":
+		world, err = util.ReadNetpbm(file)
+		if err == nil && (width != 0 || height != 0) && (len(world) != height || len(world[0]) != width) {
+			err = fmt.Errorf("image is %vx%v, expected %vx%v", len(world[0]), len(world), width, height)
+		}
+	case ".png":
+		world, err = readPng(file, width, height, threshold)
+	default:
+		read, ok := patternReaders[ext]
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown file format %q", filename, ext)
+		}
+		world, err = read(file, width, height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	return world, nil
 }
 
 // startIo should be the entrypoint of the io goroutine.
@@ -138,7 +277,9 @@ func startIo(p Params, c ioChannels) {
 		case command := <-io.channels.command:
 			switch command {
 			case ioInput:
-				io.readPgmImage()
+				// Request a filename from the distributor.
+				filename := <-io.channels.filename
+				io.readImage(filename)
 			case ioOutput:
 				io.writePgmImage()
 			case ioCheckIdle:
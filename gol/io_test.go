@@ -0,0 +1,27 @@
+package gol
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestMissingInput checks that a missing input file is reported as an event, and the game quits without panicking.
+func TestMissingInput(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "missing.rle")
+	events := make(chan Event, 10)
+	Run(Params{Turns: 1, Threads: 1, ImageWidth: 16, ImageHeight: 16, Input: input}, events, nil)
+
+	var received []Event
+	for event := range events {
+		received = append(received, event)
+	}
+	if len(received) != 2 {
+		t.Fatalf("expected IOFailed then Quitting, got %v", received)
+	}
+	if failed, ok := received[0].(IOFailed); !ok || failed.Filename != input || failed.Err == nil {
+		t.Errorf("expected IOFailed for %v, got %#v", input, received[0])
+	}
+	if state, ok := received[1].(StateChange); !ok || state.NewState != Quitting {
+		t.Errorf("expected Quitting, got %#v", received[1])
+	}
+}
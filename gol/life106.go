@@ -0,0 +1,93 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Life 1.06 patterns list the coordinates of each alive cell, which may be negative:
+//
+//	#Life 1.06
+//	0 -1
+//	1 0
+//	-1 1
+//	0 1
+//	1 1
+//
+// See https://conwaylife.com/wiki/Life_1.06
+
+const life106Header = "#Life 1.06"
+
+// readLife106 reads a Life 1.06 pattern and places it in the centre of an otherwise dead world of the given size.
+func readLife106(r io.Reader, width, height int) ([][]byte, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != life106Header {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("life 1.06: missing #Life 1.06 header")
+	}
+
+	var cells []util.Cell
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c util.Cell
+		if _, err := fmt.Sscan(text, &c.X, &c.Y); err != nil {
+			return nil, fmt.Errorf("life 1.06: invalid coordinates %q on line %v", text, line)
+		}
+		cells = append(cells, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(cells) == 0 {
+		return makeWorld(width, height), nil
+	}
+
+	minX, minY, maxX, maxY := cells[0].X, cells[0].Y, cells[0].X, cells[0].Y
+	for _, c := range cells {
+		if c.X < minX {
+			minX = c.X
+		}
+		if c.X > maxX {
+			maxX = c.X
+		}
+		if c.Y < minY {
+			minY = c.Y
+		}
+		if c.Y > maxY {
+			maxY = c.Y
+		}
+	}
+	world, offsetX, offsetY, err := centredWorld(maxX-minX+1, maxY-minY+1, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("life 1.06: %w", err)
+	}
+	for _, c := range cells {
+		world[offsetY+c.Y-minY][offsetX+c.X-minX] = 255
+	}
+	return world, nil
+}
+
+// writeLife106 writes the alive cells, such as those reported by FinalTurnComplete, as a Life 1.06 pattern.
+// Each comment is written on a #D line after the header.
+func writeLife106(w io.Writer, alive []util.Cell, comments ...string) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, life106Header)
+	for _, comment := range comments {
+		fmt.Fprintln(out, "#D", comment)
+	}
+	for _, c := range alive {
+		fmt.Fprintln(out, c.X, c.Y)
+	}
+	return out.Flush()
+}
@@ -0,0 +1,68 @@
+package gol
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// metricsHeader names the columns of a metrics file. Each row is a WorkerJoined, WorkerLost or TurnTiming event,
+// with the columns that do not apply to it left empty. Times are in milliseconds.
+var metricsHeader = []string{
+	"turn", "event", "worker", "start_row", "end_row", "reason",
+	"compute_ms", "network_ms", "assembly_ms", "turns_per_second",
+}
+
+// IsMetric reports whether an event is one written by LogMetrics, for use as a subscriber's filter.
+func IsMetric(event Event) bool {
+	switch event.(type) {
+	case WorkerJoined, WorkerLost, TurnTiming:
+		return true
+	}
+	return false
+}
+
+// LogMetrics writes the WorkerJoined, WorkerLost and TurnTiming events from events as rows of a CSV file
+// until events is closed, for benchmark analysis. Other events are skipped.
+// If the file cannot be written to, events are still taken so as not to hold up the game,
+// and the first error is returned at the end.
+func LogMetrics(w io.Writer, events <-chan Event) error {
+	out := csv.NewWriter(w)
+	err := out.Write(metricsHeader)
+	for event := range events {
+		if err != nil || !IsMetric(event) {
+			continue
+		}
+		err = out.Write(metricsRow(event))
+	}
+	out.Flush()
+	if err == nil {
+		err = out.Error()
+	}
+	return err
+}
+
+// metricsRow returns the row of a metrics file for an event accepted by IsMetric.
+func metricsRow(event Event) []string {
+	row := make([]string, len(metricsHeader))
+	row[0] = strconv.Itoa(event.GetCompletedTurns())
+	switch e := event.(type) {
+	case WorkerJoined:
+		row[1], row[2] = "joined", e.Address
+		row[3], row[4] = strconv.Itoa(e.StartY), strconv.Itoa(e.EndY)
+	case WorkerLost:
+		row[1], row[2] = "lost", e.Address
+		row[3], row[4] = strconv.Itoa(e.StartY), strconv.Itoa(e.EndY)
+		row[5] = e.Reason
+	case TurnTiming:
+		row[1] = "timing"
+		row[6], row[7], row[8] = milliseconds(e.Compute), milliseconds(e.Network), milliseconds(e.Assembly)
+		row[9] = strconv.FormatFloat(e.TurnsPerSecond, 'f', 2, 64)
+	}
+	return row
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
@@ -0,0 +1,29 @@
+package gol
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestLogMetrics checks that worker and timing events are written as rows of a CSV file, and others are skipped.
+func TestLogMetrics(t *testing.T) {
+	events := make(chan Event, 10)
+	events <- WorkerJoined{0, "127.0.0.1:8050", 0, 128}
+	events <- TurnComplete{1}
+	events <- TurnTiming{1, 1500 * time.Microsecond, 2 * time.Millisecond, 250 * time.Microsecond, 200}
+	events <- WorkerLost{2, "127.0.0.1:8051", 128, 256, "connection refused"}
+	close(events)
+
+	var buf bytes.Buffer
+	if err := LogMetrics(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	expected := "turn,event,worker,start_row,end_row,reason,compute_ms,network_ms,assembly_ms,turns_per_second\n" +
+		"0,joined,127.0.0.1:8050,0,128,,,,,\n" +
+		"1,timing,,,,,1.500,2.000,0.250,200.00\n" +
+		"2,lost,127.0.0.1:8051,128,256,connection refused,,,,\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, buf.String())
+	}
+}
@@ -0,0 +1,38 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+)
+
+// patternReader reads a pattern into a world of the given size, or of the pattern's size if width and height are 0.
+type patternReader func(r io.Reader, width, height int) ([][]byte, error)
+
+// patternReaders holds the reader for each pattern format, chosen by file extension.
+var patternReaders = map[string]patternReader{
+	".rle":   readRle,
+	".cells": readCells,
+	".lif":   readLife106,
+	".life":  readLife106,
+}
+
+// makeWorld returns a dead world of the given size.
+func makeWorld(width, height int) [][]byte {
+	world := make([][]byte, height)
+	for i := range world {
+		world[i] = make([]byte, width)
+	}
+	return world
+}
+
+// centredWorld returns a dead world of the given size, or of the pattern's size if width and height are 0,
+// along with the offset that places the pattern in the centre of it.
+func centredWorld(patternWidth, patternHeight, width, height int) (world [][]byte, offsetX, offsetY int, err error) {
+	if width == 0 && height == 0 {
+		width, height = patternWidth, patternHeight
+	}
+	if patternWidth > width || patternHeight > height {
+		return nil, 0, 0, fmt.Errorf("%vx%v pattern does not fit in a %vx%v world", patternWidth, patternHeight, width, height)
+	}
+	return makeWorld(width, height), (width - patternWidth) / 2, (height - patternHeight) / 2, nil
+}
@@ -0,0 +1,127 @@
+package gol
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPatternFormats checks that a glider in each format is read into the same world as the rle glider.
+func TestPatternFormats(t *testing.T) {
+	expected, err := readRle(strings.NewReader(gliderRle), 6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, pattern := range map[string]string{
+		".cells":      "!Name: Glider\n.O\n..O\nOOO\n\n",
+		".lif":        "#Life 1.06\n#D A comment\n0 -1\n1 0\n-1 1\n0 1\n1 1\n",
+		".lif offset": "#Life 1.06\n101 100\n102 101\n100 102\n101 102\n102 102\n",
+	} {
+		read := patternReaders[strings.Fields(name)[0]]
+		world, err := read(strings.NewReader(pattern), 6, 5)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		for y := range expected {
+			if !bytes.Equal(world[y], expected[y]) {
+				t.Errorf("%v: row %v is %v, expected %v", name, y, world[y], expected[y])
+			}
+		}
+	}
+}
+
+// TestPatternErrors checks that bad patterns are reported rather than misread.
+func TestPatternErrors(t *testing.T) {
+	for name, pattern := range map[string]string{
+		".cells too big":  "OOOOOO",
+		".cells bad cell": ".O\n.X",
+		".cells empty":    "!Name: Nothing\n",
+		".lif no header":  "0 0\n1 1\n",
+		".lif bad line":   "#Life 1.06\n0 zero\n",
+		".lif too big":    "#Life 1.06\n-3 0\n3 0\n",
+	} {
+		read := patternReaders[strings.Fields(name)[0]]
+		if _, err := read(strings.NewReader(pattern), 5, 5); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+// TestLife106RoundTrip checks that alive cells written as Life 1.06 read back into the same place.
+func TestLife106RoundTrip(t *testing.T) {
+	alive := []util.Cell{{X: 0, Y: 0}, {X: 4, Y: 1}, {X: 2, Y: 3}}
+	var buf bytes.Buffer
+	if err := writeLife106(&buf, alive); err != nil {
+		t.Fatal(err)
+	}
+	world, err := readLife106(&buf, 5, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, row := range world {
+		for _, b := range row {
+			if b != 0 {
+				count++
+			}
+		}
+	}
+	if count != len(alive) {
+		t.Fatalf("expected %v alive cells, got %v", len(alive), count)
+	}
+	for _, c := range alive {
+		if world[c.Y][c.X] != 255 {
+			t.Fatalf("cell %v is not alive after a round trip", c)
+		}
+	}
+}
+
+// TestInputSize checks that the size of the world is taken from the input file when it is not given.
+func TestInputSize(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"glider.rle":   gliderRle,
+		"glider.cells": ".O\n..O\nOOO\n",
+		"image.pgm":    "P2 4 2 1\n0 1 0 1\n1 0 1 0\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for input, expected := range map[string][2]int{
+		"glider.rle":   {3, 3},
+		"glider.cells": {3, 3},
+		"image.pgm":    {4, 2},
+	} {
+		width, height, err := InputSize(Params{Input: filepath.Join(dir, input)})
+		if err != nil {
+			t.Errorf("%v: %v", input, err)
+		} else if width != expected[0] || height != expected[1] {
+			t.Errorf("%v: expected %vx%v, got %vx%v", input, expected[0], expected[1], width, height)
+		}
+	}
+	if width, height, _ := InputSize(Params{}); width != DefaultImageSize || height != DefaultImageSize {
+		t.Errorf("expected the default size without an input file, got %vx%v", width, height)
+	}
+	if _, _, err := InputSize(Params{Input: filepath.Join(dir, "missing.pgm")}); err == nil {
+		t.Error("expected an error for a missing input file")
+	}
+}
+
+// TestOutputName checks that the output name template is filled in.
+func TestOutputName(t *testing.T) {
+	p := Params{ImageWidth: 64, ImageHeight: 32, Input: "patterns/glider.rle", OutputName: "{input}-{width}x{height}-{turns}"}
+	if name := outputName(p, 17); name != "glider-64x32-17" {
+		t.Errorf("expected glider-64x32-17, got %v", name)
+	}
+	p = Params{ImageWidth: 64, ImageHeight: 32, OutputName: DefaultOutputName}
+	if name := outputName(p, 5); name != "64x32x5" {
+		t.Errorf("expected 64x32x5, got %v", name)
+	}
+}
@@ -0,0 +1,202 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placement puts a pattern into the world at an offset, optionally rotated, reflected and tiled.
+// It is written on the command line as the pattern file followed by any options:
+//
+//	patterns/gosper.rle,x=10,y=20,rotate=90,flip=x,tile=64x64
+//
+// The pattern is reflected first and then rotated clockwise about its own centre, so that x and y are
+// always the top left corner of the result. Offsets wrap around the edges of the world, as cells do.
+type Placement struct {
+	Pattern string // any file that can be used as an input, read at its own size
+	X, Y    int
+	Rotate  int  // clockwise rotation in degrees: 0, 90, 180 or 270
+	FlipX   bool // reflect left to right
+	FlipY   bool // reflect top to bottom
+	TileX   int  // repeat the pattern every TileX columns across the world, 0 for once
+	TileY   int  // repeat the pattern every TileY rows down the world, 0 for once
+}
+
+// Placements is a list of patterns to place, which can be used as a repeatable flag.
+type Placements []Placement
+
+func (ps *Placements) String() string {
+	if ps == nil {
+		return ""
+	}
+	s := make([]string, len(*ps))
+	for i, pl := range *ps {
+		s[i] = pl.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// Set adds a placement written as described for Placement.
+func (ps *Placements) Set(value string) error {
+	pl, err := ParsePlacement(value)
+	if err != nil {
+		return err
+	}
+	*ps = append(*ps, pl)
+	return nil
+}
+
+// ParsePlacement reads a placement written as described for Placement.
+func ParsePlacement(s string) (Placement, error) {
+	fields := strings.Split(s, ",")
+	pl := Placement{Pattern: fields[0]}
+	if pl.Pattern == "" {
+		return pl, fmt.Errorf("placement %q has no pattern file", s)
+	}
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return pl, fmt.Errorf("placement option %q is not of the form key=value", field)
+		}
+		key, value := parts[0], parts[1]
+		var err error
+		switch key {
+		case "x":
+			pl.X, err = strconv.Atoi(value)
+		case "y":
+			pl.Y, err = strconv.Atoi(value)
+		case "rotate":
+			pl.Rotate, err = strconv.Atoi(value)
+			if err == nil && (pl.Rotate%90 != 0 || pl.Rotate < 0 || pl.Rotate >= 360) {
+				err = fmt.Errorf("rotation must be 0, 90, 180 or 270")
+			}
+		case "flip":
+			pl.FlipX = strings.Contains(value, "x")
+			pl.FlipY = strings.Contains(value, "y")
+			if strings.Trim(value, "xy") != "" {
+				err = fmt.Errorf("flip must be x, y or xy")
+			}
+		case "tile":
+			size := strings.SplitN(value, "x", 2)
+			if len(size) != 2 {
+				err = fmt.Errorf("tile must be of the form WxH")
+				break
+			}
+			if pl.TileX, err = strconv.Atoi(size[0]); err == nil {
+				pl.TileY, err = strconv.Atoi(size[1])
+			}
+			if err == nil && (pl.TileX < 0 || pl.TileY < 0) {
+				err = fmt.Errorf("tile spacing must not be negative")
+			}
+		default:
+			err = fmt.Errorf("unknown option")
+		}
+		if err != nil {
+			return pl, fmt.Errorf("placement option %q: %w", field, err)
+		}
+	}
+	return pl, nil
+}
+
+func (pl Placement) String() string {
+	s := fmt.Sprintf("%v,x=%v,y=%v", pl.Pattern, pl.X, pl.Y)
+	if pl.Rotate != 0 {
+		s += fmt.Sprintf(",rotate=%v", pl.Rotate)
+	}
+	if pl.FlipX || pl.FlipY {
+		s += ",flip="
+		if pl.FlipX {
+			s += "x"
+		}
+		if pl.FlipY {
+			s += "y"
+		}
+	}
+	if pl.TileX != 0 || pl.TileY != 0 {
+		s += fmt.Sprintf(",tile=%vx%v", pl.TileX, pl.TileY)
+	}
+	return s
+}
+
+// place reads each pattern and adds its alive cells to world.
+func place(world [][]byte, placements []Placement, threshold int) error {
+	for _, pl := range placements {
+		pattern, err := readWorld(pl.Pattern, 0, 0, threshold)
+		if err != nil {
+			return err
+		}
+		pl.stamp(world, pl.transform(pattern))
+	}
+	return nil
+}
+
+// transform reflects and then rotates pattern.
+func (pl Placement) transform(pattern [][]byte) [][]byte {
+	height := len(pattern)
+	width := 0
+	if height > 0 {
+		width = len(pattern[0])
+	}
+	if pl.FlipX || pl.FlipY {
+		flipped := makeWorld(width, height)
+		for y, row := range pattern {
+			for x, b := range row {
+				fx, fy := x, y
+				if pl.FlipX {
+					fx = width - 1 - x
+				}
+				if pl.FlipY {
+					fy = height - 1 - y
+				}
+				flipped[fy][fx] = b
+			}
+		}
+		pattern = flipped
+	}
+	for r := 0; r < pl.Rotate/90; r++ {
+		// a quarter turn clockwise takes (x, y) to (height-1-y, x)
+		rotated := makeWorld(height, width)
+		for y, row := range pattern {
+			for x, b := range row {
+				rotated[x][height-1-y] = b
+			}
+		}
+		pattern = rotated
+		width, height = height, width
+	}
+	return pattern
+}
+
+// stamp adds the alive cells of pattern to world at the placement's offset, and at every tile spacing after it.
+func (pl Placement) stamp(world [][]byte, pattern [][]byte) {
+	height := len(world)
+	if height == 0 {
+		return
+	}
+	width := len(world[0])
+	tilesX, tilesY := 1, 1
+	if pl.TileX > 0 {
+		tilesX = (width + pl.TileX - 1) / pl.TileX
+	}
+	if pl.TileY > 0 {
+		tilesY = (height + pl.TileY - 1) / pl.TileY
+	}
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			for y, row := range pattern {
+				for x, b := range row {
+					if b != 0 {
+						wx := mod(pl.X+tx*pl.TileX+x, width)
+						wy := mod(pl.Y+ty*pl.TileY+y, height)
+						world[wy][wx] = 255
+					}
+				}
+			}
+		}
+	}
+}
+
+func mod(x, m int) int {
+	return (x%m + m) % m
+}
@@ -0,0 +1,98 @@
+package gol
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestParsePlacement checks that placements are read from flags, and bad options refused.
+func TestParsePlacement(t *testing.T) {
+	pl, err := ParsePlacement("gun.rle,x=10,y=-3,rotate=270,flip=xy,tile=64x32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Placement{Pattern: "gun.rle", X: 10, Y: -3, Rotate: 270, FlipX: true, FlipY: true, TileX: 64, TileY: 32}
+	if pl != expected {
+		t.Errorf("expected %+v, got %+v", expected, pl)
+	}
+	if again, err := ParsePlacement(pl.String()); err != nil || again != pl {
+		t.Errorf("%v does not parse back to the same placement: %+v, %v", pl, again, err)
+	}
+
+	for _, s := range []string{",x=1", "gun.rle,x", "gun.rle,rotate=45", "gun.rle,flip=z", "gun.rle,tile=8", "gun.rle,size=3"} {
+		if _, err := ParsePlacement(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+// TestTransform checks reflection and rotation of an L shape.
+func TestTransform(t *testing.T) {
+	// #.
+	// #.
+	// ##
+	l := [][]byte{{255, 0}, {255, 0}, {255, 255}}
+	for pl, expected := range map[Placement][]string{
+		{}:                         {"#.", "#.", "##"},
+		{Rotate: 90}:               {"###", "#.."},
+		{Rotate: 180}:              {"##", ".#", ".#"},
+		{Rotate: 270}:              {"..#", "###"},
+		{FlipX: true}:              {".#", ".#", "##"},
+		{FlipY: true}:              {"##", "#.", "#."},
+		{FlipX: true, Rotate: 90}:  {"#..", "###"},
+		{FlipX: true, FlipY: true}: {"##", ".#", ".#"},
+	} {
+		if got := pl.transform(l); !matches(got, expected) {
+			t.Errorf("%+v: expected %v, got %v", pl, expected, got)
+		}
+	}
+}
+
+// TestPlace checks that patterns are added to the world, wrapping around the edges and tiled.
+func TestPlace(t *testing.T) {
+	dot := filepath.Join(t.TempDir(), "dot.cells")
+	if err := ioutil.WriteFile(dot, []byte("O\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	world := makeWorld(8, 4)
+	world[0][0] = 255
+	err := place(world, []Placement{
+		{Pattern: dot, X: -1, Y: 1},
+		{Pattern: dot, X: 1, Y: 2, TileX: 3},
+	}, DefaultPngThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"#.......",
+		".......#",
+		".#..#..#",
+		"........",
+	}
+	if !matches(world, expected) {
+		t.Fatalf("expected %v, got %v", expected, world)
+	}
+
+	if err := place(world, []Placement{{Pattern: dot + ".missing"}}, DefaultPngThreshold); err == nil {
+		t.Error("expected an error for a missing pattern")
+	}
+}
+
+// matches reports whether world has the alive (#) and dead cells drawn in expected.
+func matches(world [][]byte, expected []string) bool {
+	if len(world) != len(expected) {
+		return false
+	}
+	for y, row := range expected {
+		if len(world[y]) != len(row) {
+			return false
+		}
+		for x, c := range row {
+			if (world[y][x] != 0) != (c == '#') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package gol
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// DefaultPngThreshold is the brightness at or above which a pixel of an imported png is alive.
+const DefaultPngThreshold = 128
+
+// readPng reads a png image and places it in the centre of an otherwise dead world of the given size.
+// Pixels at least as bright as threshold are alive, so transparent pixels are always dead.
+func readPng(r io.Reader, width, height, threshold int) ([][]byte, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("png: %w", err)
+	}
+	bounds := img.Bounds()
+	world, offsetX, offsetY, err := centredWorld(bounds.Dx(), bounds.Dy(), width, height)
+	if err != nil {
+		return nil, fmt.Errorf("png: %w", err)
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+			if int(gray.Y) >= threshold {
+				world[offsetY+y][offsetX+x] = 255
+			}
+		}
+	}
+	return world, nil
+}
+
+// writePng writes world as a greyscale png, with each cell drawn as a square of scale by scale pixels.
+func writePng(w io.Writer, world [][]byte, scale int) error {
+	height := len(world)
+	width := 0
+	if height > 0 {
+		width = len(world[0])
+	}
+	img := image.NewGray(image.Rect(0, 0, width*scale, height*scale))
+	for y, row := range world {
+		for x, b := range row {
+			if b == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				line := img.Pix[(y*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					line[x*scale+dx] = b
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
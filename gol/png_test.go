@@ -0,0 +1,62 @@
+package gol
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// TestReadPng checks that pixels are thresholded and the image is centred in the world.
+func TestReadPng(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{R: 100, G: 100, B: 100, A: 255})
+	img.Set(2, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 0})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	for threshold, expected := range map[int][]byte{
+		128: {0, 255, 0, 0, 0},
+		50:  {0, 255, 255, 0, 0},
+	} {
+		world, err := readPng(bytes.NewReader(buf.Bytes()), 5, 3, threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(world[1], expected) || !bytes.Equal(world[0], make([]byte, 5)) {
+			t.Errorf("threshold %v: got %v, expected the middle row to be %v", threshold, world, expected)
+		}
+	}
+
+	if _, err := readPng(bytes.NewReader(buf.Bytes()), 2, 2, 128); err == nil {
+		t.Error("expected an error for an image bigger than the world")
+	}
+}
+
+// TestWritePngScale checks that each cell is drawn as a square of scale pixels.
+func TestWritePngScale(t *testing.T) {
+	world := [][]byte{{255, 0}, {0, 255}}
+	var buf bytes.Buffer
+	if err := writePng(&buf, world, 3); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 6 || img.Bounds().Dy() != 6 {
+		t.Fatalf("expected a 6x6 image, got %v", img.Bounds())
+	}
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			if (gray.Y != 0) != (world[y/3][x/3] != 0) {
+				t.Fatalf("pixel (%v, %v) does not match cell (%v, %v)", x, y, x/3, y/3)
+			}
+		}
+	}
+}
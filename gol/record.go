@@ -0,0 +1,222 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Recording asks for every Nth turn of a run to be saved, either as an animated gif or as a numbered sequence
+// of pgm or png frames in the frames directory of the output directory. It is written on the command line as
+// the format followed by any options:
+//
+//	gif,from=0,to=200,every=2,scale=4,delay=5,alive=#ffcc00,dead=#202020
+//
+// The frames are built from the cells flipped after each turn, so they are recorded with or without a window.
+type Recording struct {
+	Format string      // gif, pgm or png
+	From   int         // first turn to record
+	To     int         // last turn to record, 0 for the end of the run
+	Every  int         // record every Nth turn from From, 0 for 1
+	Scale  int         // width in pixels of each cell, 0 for 1
+	Delay  int         // time between gif frames in hundredths of a second, 0 for 10
+	Alive  color.Color // colour of alive cells in gif and png frames, nil for white
+	Dead   color.Color // colour of dead cells in gif and png frames, nil for black
+}
+
+// ParseRecording reads a recording written as described for Recording.
+func ParseRecording(s string) (Recording, error) {
+	fields := strings.Split(s, ",")
+	r := Recording{Format: fields[0]}
+	switch r.Format {
+	case "gif", "pgm", "png":
+	default:
+		return r, fmt.Errorf("recording format %q is not gif, pgm or png", r.Format)
+	}
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return r, fmt.Errorf("recording option %q is not of the form key=value", field)
+		}
+		key, value := parts[0], parts[1]
+		var err error
+		switch key {
+		case "from":
+			r.From, err = strconv.Atoi(value)
+		case "to":
+			r.To, err = strconv.Atoi(value)
+		case "every":
+			r.Every, err = strconv.Atoi(value)
+		case "scale":
+			r.Scale, err = strconv.Atoi(value)
+		case "delay":
+			r.Delay, err = strconv.Atoi(value)
+		case "alive":
+			r.Alive, err = parseColour(value)
+		case "dead":
+			r.Dead, err = parseColour(value)
+		default:
+			err = fmt.Errorf("unknown option")
+		}
+		if err == nil && (r.From < 0 || r.To < 0 || r.Every < 0 || r.Scale < 0 || r.Delay < 0) {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			return r, fmt.Errorf("recording option %q: %w", field, err)
+		}
+	}
+	return r, nil
+}
+
+// parseColour reads a colour written as #rrggbb.
+func parseColour(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return nil, fmt.Errorf("colour %q is not of the form #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, nil
+}
+
+func (r Recording) withDefaults() Recording {
+	if r.Every == 0 {
+		r.Every = 1
+	}
+	if r.Scale == 0 {
+		r.Scale = 1
+	}
+	if r.Delay == 0 {
+		r.Delay = 10
+	}
+	if r.Alive == nil {
+		r.Alive = color.White
+	}
+	if r.Dead == nil {
+		r.Dead = color.Black
+	}
+	return r
+}
+
+// recorder follows the world from the cells flipped after each turn, saving the turns asked for.
+type recorder struct {
+	r        Recording
+	p        Params
+	world    [][]byte
+	lastTurn int
+	gif      gif.GIF
+}
+
+// newRecorder starts recording from a copy of the initial world, which is saved if turn 0 is asked for.
+func newRecorder(p Params, world [][]byte) (*recorder, error) {
+	rec := &recorder{r: p.Record.withDefaults(), p: p, world: make([][]byte, len(world))}
+	for y, row := range world {
+		rec.world[y] = append([]byte(nil), row...)
+	}
+	return rec, rec.capture(0)
+}
+
+// turnComplete applies the cells flipped by a turn, and saves the world if it is one of the turns asked for.
+func (rec *recorder) turnComplete(turn int, flipped []util.Cell) error {
+	rec.edit(flipped)
+	return rec.capture(turn)
+}
+
+// edit applies cells flipped without a turn passing, which are saved along with the next turn asked for.
+func (rec *recorder) edit(flipped []util.Cell) {
+	for _, c := range flipped {
+		rec.world[c.Y][c.X] ^= 255
+	}
+}
+
+func (rec *recorder) capture(turn int) error {
+	r := rec.r
+	if turn < r.From || (r.To != 0 && turn > r.To) || (turn-r.From)%r.Every != 0 {
+		return nil
+	}
+	rec.lastTurn = turn
+	frame := rec.frame()
+	if r.Format == "gif" {
+		rec.gif.Image = append(rec.gif.Image, frame)
+		rec.gif.Delay = append(rec.gif.Delay, r.Delay)
+		return nil
+	}
+
+	dir := filepath.Join(rec.p.OutputDir, "frames")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(dir, fmt.Sprintf("frame%06d.%v", turn, r.Format)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if r.Format == "png" {
+		return png.Encode(file, frame)
+	}
+	out := bufio.NewWriter(file)
+	fmt.Fprintf(out, "P5\n%v %v\n255\n", frame.Rect.Dx(), frame.Rect.Dy())
+	for _, index := range frame.Pix {
+		out.WriteByte(index * 255)
+	}
+	return out.Flush()
+}
+
+// frame draws the world with each cell as a square of Scale pixels, in a palette of the dead and alive colours.
+func (rec *recorder) frame() *image.Paletted {
+	scale := rec.r.Scale
+	height := len(rec.world)
+	width := 0
+	if height > 0 {
+		width = len(rec.world[0])
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), color.Palette{rec.r.Dead, rec.r.Alive})
+	for y, row := range rec.world {
+		for x, b := range row {
+			if b == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				line := img.Pix[(y*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					line[x*scale+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// finish writes the gif, named like the other output files after the last turn recorded.
+// It returns the name of the file, or "" if there was nothing to write.
+func (rec *recorder) finish() (string, error) {
+	if rec.r.Format != "gif" || len(rec.gif.Image) == 0 {
+		return "", nil
+	}
+	filename := outputName(rec.p, rec.lastTurn) + ".gif"
+	if err := os.MkdirAll(rec.p.OutputDir, os.ModePerm); err != nil {
+		return filename, err
+	}
+	file, err := os.Create(filepath.Join(rec.p.OutputDir, filename))
+	if err != nil {
+		return filename, err
+	}
+	defer file.Close()
+	out := bufio.NewWriter(file)
+	if err := gif.EncodeAll(out, &rec.gif); err != nil {
+		return filename, err
+	}
+	if err := out.Flush(); err != nil {
+		return filename, err
+	}
+	fmt.Println("File", filename, "output done!")
+	return filename, nil
+}
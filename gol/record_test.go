@@ -0,0 +1,86 @@
+package gol
+
+import (
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestParseRecording checks that recordings are read from flags, and bad options refused.
+func TestParseRecording(t *testing.T) {
+	r, err := ParseRecording("gif,from=2,to=20,every=3,scale=4,delay=5,alive=#ffcc00,dead=#000010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format != "gif" || r.From != 2 || r.To != 20 || r.Every != 3 || r.Scale != 4 || r.Delay != 5 {
+		t.Errorf("unexpected recording %+v", r)
+	}
+	if r.Alive != (color.RGBA{R: 0xff, G: 0xcc, A: 0xff}) || r.Dead != (color.RGBA{B: 0x10, A: 0xff}) {
+		t.Errorf("unexpected colours %v and %v", r.Alive, r.Dead)
+	}
+
+	for _, s := range []string{"mp4", "gif,every", "gif,every=-1", "png,alive=red", "pgm,fps=3"} {
+		if _, err := ParseRecording(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+// TestRecorder checks that the turns asked for are recorded from the cells flipped after each turn.
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	p := Params{ImageWidth: 3, ImageHeight: 3, OutputDir: dir, OutputName: DefaultOutputName}
+	world := makeWorld(3, 3)
+	world[1][0], world[1][1], world[1][2] = 255, 255, 255
+	blinker := []util.Cell{{X: 0, Y: 1}, {X: 2, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 2}}
+
+	for _, format := range []string{"gif", "png", "pgm"} {
+		p.Record = &Recording{Format: format, From: 1, To: 4, Every: 2, Scale: 2}
+		rec, err := newRecorder(p, world)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for turn := 1; turn <= 6; turn++ {
+			if err := rec.turnComplete(turn, blinker); err != nil {
+				t.Fatal(err)
+			}
+		}
+		filename, err := rec.finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if format != "gif" {
+			for _, frame := range []string{"frame000001", "frame000003"} {
+				if _, err := os.Stat(filepath.Join(dir, "frames", frame+"."+format)); err != nil {
+					t.Error(err)
+				}
+			}
+			continue
+		}
+		if filename != "3x3x3.gif" {
+			t.Errorf("expected the gif to be named after turn 3, got %v", filename)
+		}
+		file, err := os.Open(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		animation, err := gif.DecodeAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(animation.Image) != 2 {
+			t.Fatalf("expected 2 frames, got %v", len(animation.Image))
+		}
+		// after an odd number of turns the blinker is vertical
+		frame := animation.Image[0]
+		if frame.Rect.Dx() != 6 || frame.ColorIndexAt(2, 0) != 1 || frame.ColorIndexAt(0, 2) != 0 {
+			t.Error("the first frame does not show a vertical blinker at a scale of 2")
+		}
+	}
+}
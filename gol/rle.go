@@ -0,0 +1,204 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Run Length Encoded (RLE) patterns are the usual way of sharing Life patterns.
+// A pattern is a header line followed by runs of cells, for example a glider:
+//
+//	#N Glider
+//	x = 3, y = 3, rule = B3/S23
+//	bo$2bo$3o!
+//
+// where b is a dead cell, o is an alive cell, $ ends a row and ! ends the pattern.
+// See https://conwaylife.com/wiki/Run_Length_Encoded
+
+// lifeRule is the only rule the servers know how to run.
+const lifeRule = "B3/S23"
+
+// rleLineLength is the longest line written, as recommended by the format.
+const rleLineLength = 70
+
+// readRle reads an RLE pattern and places it in the centre of an otherwise dead world of the given size.
+func readRle(r io.Reader, width, height int) ([][]byte, error) {
+	scanner := bufio.NewScanner(r)
+	var patternWidth, patternHeight int
+	headerFound := false
+	var body strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case !headerFound:
+			var err error
+			patternWidth, patternHeight, err = parseRleHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			headerFound = true
+		default:
+			body.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !headerFound {
+		return nil, errors.New("rle: missing header line")
+	}
+	world, offsetX, offsetY, err := centredWorld(patternWidth, patternHeight, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("rle: %w", err)
+	}
+
+	x, y, count := 0, 0, 0
+	for _, c := range body.String() {
+		switch {
+		case c >= '0' && c <= '9':
+			count = count*10 + int(c-'0')
+			continue
+		case c == ' ' || c == '\t':
+			continue
+		}
+		if count == 0 {
+			count = 1
+		}
+		switch c {
+		case 'b':
+			x += count
+		case 'o':
+			if x+count > patternWidth || y >= patternHeight {
+				return nil, fmt.Errorf("rle: cells outside of the %vx%v pattern", patternWidth, patternHeight)
+			}
+			for i := 0; i < count; i++ {
+				world[offsetY+y][offsetX+x] = 255
+				x++
+			}
+		case '$':
+			y += count
+			x = 0
+		case '!':
+			return world, nil
+		default:
+			return nil, fmt.Errorf("rle: unexpected %q in pattern", c)
+		}
+		count = 0
+	}
+	return nil, errors.New("rle: pattern is not terminated by !")
+}
+
+// parseRleHeader reads the size from a header line such as "x = 3, y = 3, rule = B3/S23".
+// Patterns for any rule other than Life are refused, since that is all the servers can run.
+func parseRleHeader(line string) (width, height int, err error) {
+	for _, field := range strings.Split(line, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return 0, 0, fmt.Errorf("rle: invalid header %q", line)
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		switch key {
+		case "x":
+			width, err = strconv.Atoi(value)
+		case "y":
+			height, err = strconv.Atoi(value)
+		case "rule":
+			var rule string
+			rule, err = normaliseRule(value)
+			if err == nil && rule != lifeRule {
+				err = fmt.Errorf("rle: rule %v is not supported, only %v", value, lifeRule)
+			}
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("rle: invalid pattern size in header %q", line)
+	}
+	return width, height, nil
+}
+
+// normaliseRule converts a rule in either B/S notation (B3/S23) or the older S/B notation (23/3) to B/S notation.
+func normaliseRule(rule string) (string, error) {
+	parts := strings.Split(strings.ToUpper(rule), "/")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("rle: invalid rule %q", rule)
+	}
+	birth, survival := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(birth, "B") && strings.HasPrefix(survival, "S"):
+	case strings.HasPrefix(birth, "S") && strings.HasPrefix(survival, "B"):
+		birth, survival = survival, birth
+	default:
+		// S/B notation without letters
+		birth, survival = "B"+survival, "S"+birth
+	}
+	return birth + "/" + survival, nil
+}
+
+// writeRle writes the whole of world as an RLE pattern, so that reading it back into a world of the same size
+// gives the same world. Each comment is written on a #C line before the header.
+func writeRle(w io.Writer, world [][]byte, comments ...string) error {
+	height := len(world)
+	width := 0
+	if height > 0 {
+		width = len(world[0])
+	}
+
+	out := bufio.NewWriter(w)
+	for _, comment := range comments {
+		fmt.Fprintln(out, "#C", comment)
+	}
+	fmt.Fprintf(out, "x = %v, y = %v, rule = %v\n", width, height, lifeRule)
+
+	lineLength := 0
+	emit := func(count int, tag byte) {
+		item := string(tag)
+		if count > 1 {
+			item = strconv.Itoa(count) + item
+		}
+		if lineLength+len(item) > rleLineLength {
+			out.WriteByte('\n')
+			lineLength = 0
+		}
+		out.WriteString(item)
+		lineLength += len(item)
+	}
+
+	pendingRows := 0 // row ends not yet written, so that runs of empty rows become a single n$
+	for _, row := range world {
+		// trailing dead cells in a row are left out
+		end := len(row)
+		for end > 0 && row[end-1] == 0 {
+			end--
+		}
+		if end > 0 && pendingRows > 0 {
+			emit(pendingRows, '$')
+			pendingRows = 0
+		}
+		for x := 0; x < end; {
+			run := 1
+			for x+run < end && (row[x+run] == 0) == (row[x] == 0) {
+				run++
+			}
+			if row[x] == 0 {
+				emit(run, 'b')
+			} else {
+				emit(run, 'o')
+			}
+			x += run
+		}
+		pendingRows++
+	}
+	emit(1, '!')
+	out.WriteByte('\n')
+	return out.Flush()
+}
@@ -0,0 +1,85 @@
+package gol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const gliderRle = `#N Glider
+#C A comment
+x = 3, y = 3, rule = B3/S23
+bo$2bo$
+3o!
+`
+
+// TestReadRle checks that a pattern is centred in the world.
+func TestReadRle(t *testing.T) {
+	world, err := readRle(strings.NewReader(gliderRle), 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		".....",
+		"..#..",
+		"...#.",
+		".###.",
+		".....",
+	}
+	for y, row := range expected {
+		for x, c := range row {
+			if (world[y][x] == 255) != (c == '#') {
+				t.Fatalf("cell (%v, %v) is %v, expected %q", x, y, world[y][x], c)
+			}
+		}
+	}
+}
+
+// TestReadRleErrors checks that bad patterns are reported rather than misread.
+func TestReadRleErrors(t *testing.T) {
+	for name, rle := range map[string]string{
+		"too big":         "x = 6, y = 1\n6o!",
+		"other rule":      "x = 3, y = 3, rule = B36/S23\nbo$2bo$3o!",
+		"no header":       "bo$2bo$3o!",
+		"not terminated":  "x = 3, y = 3\nbo$2bo$3o",
+		"cells past edge": "x = 2, y = 1\n3o!",
+	} {
+		if _, err := readRle(strings.NewReader(rle), 5, 5); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+	if _, err := readRle(strings.NewReader("x = 3, y = 3, rule = 23/3\nbo$2bo$3o!"), 5, 5); err != nil {
+		t.Errorf("S/B notation: %v", err)
+	}
+}
+
+// TestRleRoundTrip checks that writing a world and reading it back gives the same world.
+func TestRleRoundTrip(t *testing.T) {
+	world := make([][]byte, 80)
+	for y := range world {
+		world[y] = make([]byte, 100)
+		for x := range world[y] {
+			if (x*7+y*13)%5 == 0 && y%9 != 0 {
+				world[y][x] = 255
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := writeRle(&buf, world); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) > rleLineLength {
+			t.Fatalf("line longer than %v characters: %q", rleLineLength, line)
+		}
+	}
+	read, err := readRle(&buf, 100, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range world {
+		if !bytes.Equal(world[y], read[y]) {
+			t.Fatalf("row %v differs after a round trip", y)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// DefaultSoupDensity is used in place of a zero Soup.Density.
+const DefaultSoupDensity = 0.5
+
+// Soup describes a random starting world, which is the same every time for the same seed.
+// The soup fills a region in the centre of an otherwise dead world, with one of these symmetries:
+//
+//	C1  none
+//	C2  unchanged by a half turn
+//	D4  unchanged by reflection left to right and top to bottom
+//	D8  unchanged by any rotation or reflection of the square, so the region must be square
+type Soup struct {
+	Seed     int64   // 0 for a seed taken from the clock, which is recorded in the output
+	Density  float64 // fraction of the region that is alive, 0 for DefaultSoupDensity
+	Symmetry string  // C1, C2, D4 or D8, "" for C1
+	Width    int     // width of the region, 0 for the width of the world
+	Height   int     // height of the region, 0 for the height of the world
+}
+
+// String describes the soup, for recording in output files.
+func (s Soup) String() string {
+	return fmt.Sprintf("soup seed=%v density=%v symmetry=%v region=%vx%v", s.Seed, s.Density, s.Symmetry, s.Width, s.Height)
+}
+
+// withDefaults fills in the zero fields of s for a world of the given size.
+func (s Soup) withDefaults(width, height int) Soup {
+	if s.Density == 0 {
+		s.Density = DefaultSoupDensity
+	}
+	if s.Symmetry == "" {
+		s.Symmetry = "C1"
+	}
+	if s.Width == 0 {
+		s.Width = width
+	}
+	if s.Height == 0 {
+		s.Height = height
+	}
+	return s
+}
+
+// generateSoup returns a world of the given size with the soup in the centre of it.
+func generateSoup(s Soup, width, height int) ([][]byte, error) {
+	s = s.withDefaults(width, height)
+	if s.Density < 0 || s.Density > 1 {
+		return nil, fmt.Errorf("soup density %v is not between 0 and 1", s.Density)
+	}
+	if s.Width < 0 || s.Height < 0 {
+		return nil, fmt.Errorf("invalid soup region %vx%v", s.Width, s.Height)
+	}
+
+	// orbit lists the cells that must match (x, y) for the soup to have its symmetry
+	w, h := s.Width, s.Height
+	var orbit func(x, y int) [][2]int
+	switch s.Symmetry {
+	case "C1":
+		orbit = func(x, y int) [][2]int { return [][2]int{{x, y}} }
+	case "C2":
+		orbit = func(x, y int) [][2]int { return [][2]int{{x, y}, {w - 1 - x, h - 1 - y}} }
+	case "D4":
+		orbit = func(x, y int) [][2]int {
+			return [][2]int{{x, y}, {w - 1 - x, y}, {x, h - 1 - y}, {w - 1 - x, h - 1 - y}}
+		}
+	case "D8":
+		if w != h {
+			return nil, fmt.Errorf("a D8 soup needs a square region, not %vx%v", w, h)
+		}
+		orbit = func(x, y int) [][2]int {
+			return [][2]int{
+				{x, y}, {w - 1 - x, y}, {x, h - 1 - y}, {w - 1 - x, h - 1 - y},
+				{y, x}, {h - 1 - y, x}, {y, w - 1 - x}, {h - 1 - y, w - 1 - x},
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown soup symmetry %q, expected C1, C2, D4 or D8", s.Symmetry)
+	}
+
+	world, offsetX, offsetY, err := centredWorld(w, h, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("soup: %w", err)
+	}
+	// every cell is drawn in the same order whatever the symmetry, then each orbit takes the value of its first cell
+	random := rand.New(rand.NewSource(s.Seed))
+	region := make([]bool, w*h)
+	for i := range region {
+		region[i] = random.Float64() < s.Density
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			first := y*w + x
+			for _, c := range orbit(x, y) {
+				if i := c[1]*w + c[0]; i < first {
+					first = i
+				}
+			}
+			if region[first] {
+				world[offsetY+y][offsetX+x] = 255
+			}
+		}
+	}
+	return world, nil
+}
@@ -0,0 +1,95 @@
+package gol
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSoupSeed checks that the same seed always gives the same soup, and a different seed a different one.
+func TestSoupSeed(t *testing.T) {
+	a, err := generateSoup(Soup{Seed: 42}, 32, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generateSoup(Soup{Seed: 42}, 32, 32)
+	c, _ := generateSoup(Soup{Seed: 43}, 32, 32)
+	same, differs := true, false
+	for y := range a {
+		same = same && bytes.Equal(a[y], b[y])
+		differs = differs || !bytes.Equal(a[y], c[y])
+	}
+	if !same {
+		t.Error("soups with the same seed differ")
+	}
+	if !differs {
+		t.Error("soups with different seeds are the same")
+	}
+}
+
+// TestSoupRegion checks the density of the soup and that it stays within its region.
+func TestSoupRegion(t *testing.T) {
+	world, err := generateSoup(Soup{Seed: 1, Density: 0.25, Width: 40, Height: 20}, 100, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alive := 0
+	for y, row := range world {
+		for x, b := range row {
+			if b == 0 {
+				continue
+			}
+			alive++
+			if x < 30 || x >= 70 || y < 20 || y >= 40 {
+				t.Fatalf("cell (%v, %v) is outside of the soup", x, y)
+			}
+		}
+	}
+	if alive < 150 || alive > 250 {
+		t.Errorf("expected about 200 alive cells at a density of 0.25, got %v", alive)
+	}
+
+	if _, err := generateSoup(Soup{Width: 101}, 100, 60); err == nil {
+		t.Error("expected an error for a soup bigger than the world")
+	}
+	if _, err := generateSoup(Soup{Density: 2}, 10, 10); err == nil {
+		t.Error("expected an error for a density above 1")
+	}
+}
+
+// TestSoupSymmetry checks that each symmetry holds.
+func TestSoupSymmetry(t *testing.T) {
+	const n = 9
+	for symmetry, images := range map[string][]func(x, y int) (int, int){
+		"C2": {func(x, y int) (int, int) { return n - 1 - x, n - 1 - y }},
+		"D4": {
+			func(x, y int) (int, int) { return n - 1 - x, y },
+			func(x, y int) (int, int) { return x, n - 1 - y },
+		},
+		"D8": {
+			func(x, y int) (int, int) { return n - 1 - x, y },
+			func(x, y int) (int, int) { return y, x },
+		},
+	} {
+		world, err := generateSoup(Soup{Seed: 7, Symmetry: symmetry}, n, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				for _, image := range images {
+					ix, iy := image(x, y)
+					if world[y][x] != world[iy][ix] {
+						t.Fatalf("%v: cell (%v, %v) does not match (%v, %v)", symmetry, x, y, ix, iy)
+					}
+				}
+			}
+		}
+	}
+
+	if _, err := generateSoup(Soup{Symmetry: "D8", Width: 4, Height: 3}, 10, 10); err == nil {
+		t.Error("expected an error for a D8 soup in a region that is not square")
+	}
+	if _, err := generateSoup(Soup{Symmetry: "C4"}, 10, 10); err == nil {
+		t.Error("expected an error for an unknown symmetry")
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
 
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
@@ -23,14 +26,14 @@ func main() {
 	flag.IntVar(
 		&params.ImageWidth,
 		"w",
-		512,
-		"Specify the width of the image. Defaults to 512.")
+		0,
+		"Specify the width of the image. Defaults to the width of the input file, or 512.")
 
 	flag.IntVar(
 		&params.ImageHeight,
 		"h",
-		512,
-		"Specify the height of the image. Defaults to 512.")
+		0,
+		"Specify the height of the image. Defaults to the height of the input file, or 512.")
 
 	flag.IntVar(
 		&params.Turns,
@@ -38,6 +41,153 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.DurationVar(
+		&params.RPCTimeout,
+		"timeout",
+		gol.DefaultRPCTimeout,
+		"Specify the deadline for each call to the broker. Negative values disable it, and 0 means 10s. Defaults to 10s.")
+
+	flag.StringVar(
+		&params.Compression,
+		"compression",
+		stubs.Flate,
+		"Specify the encoding used to compress worlds sent to and from the broker, or none if empty. Defaults to flate.")
+
+	flag.StringVar(
+		&params.Input,
+		"input",
+		"",
+		"Specify a file to load instead of images/WxH.pgm. Png images and patterns in .rle, .cells or Life 1.06 (.lif, .life) files are centred in the world.")
+
+	flag.Var(
+		&params.Placements,
+		"place",
+		"Place a pattern in the world, as file[,x=X][,y=Y][,rotate=90|180|270][,flip=x|y|xy][,tile=WxH]. "+
+			"May be repeated. Without -input the patterns are placed in a dead world.")
+
+	soup := flag.Bool(
+		"soup",
+		false,
+		"Start from a random soup instead of an input file.")
+
+	var soupParams gol.Soup
+	flag.Int64Var(
+		&soupParams.Seed,
+		"seed",
+		0,
+		"Specify the seed of the soup, so that a run can be repeated. Defaults to one taken from the clock.")
+
+	flag.Float64Var(
+		&soupParams.Density,
+		"density",
+		gol.DefaultSoupDensity,
+		"Specify the fraction of the soup that is alive. Defaults to 0.5.")
+
+	flag.StringVar(
+		&soupParams.Symmetry,
+		"symmetry",
+		"C1",
+		"Specify the symmetry of the soup: C1, C2, D4 or D8. Defaults to C1.")
+
+	soupSize := flag.String(
+		"soupSize",
+		"",
+		"Specify the size of the soup as WxH, centred in the world. Defaults to the whole world.")
+
+	eventLog := flag.String(
+		"eventLog",
+		"",
+		"Record every event to a file, which can be replayed with 'go run ./replay FILE'.")
+
+	metrics := flag.String(
+		"metrics",
+		"",
+		"Write the workers joining and leaving, and the time taken by each turn, to a CSV file.")
+
+	flag.BoolVar(
+		&params.ReportTiming,
+		"timing",
+		false,
+		"Report the time taken by each turn, split into computing, network and assembly time.")
+
+	flag.IntVar(
+		&params.DetectPeriod,
+		"period",
+		0,
+		"Look for the world repeating itself with a period of up to this many turns. Defaults to not looking.")
+
+	flag.BoolVar(
+		&params.SkipCycles,
+		"skipCycles",
+		false,
+		"Once the world is found to repeat, jump to the last turn instead of running the turns in between.")
+
+	regions := flag.String(
+		"regions",
+		"",
+		"Report the alive cells, births and deaths of each tile of a grid of CxR tiles every 2s. Defaults to none.")
+
+	record := flag.String(
+		"record",
+		"",
+		"Record turns as an animated gif or numbered pgm or png frames, as "+
+			"gif|pgm|png[,from=A][,to=B][,every=N][,scale=S][,delay=D][,alive=#rrggbb][,dead=#rrggbb].")
+
+	flag.StringVar(
+		&params.OutputDir,
+		"out",
+		"out",
+		"Specify the directory that output files are written to. Defaults to out.")
+
+	flag.StringVar(
+		&params.OutputName,
+		"name",
+		gol.DefaultOutputName,
+		"Specify the template for the names of output files, in which {width}, {height}, {turns} and {input} are replaced.")
+
+	flag.IntVar(
+		&params.PngThreshold,
+		"threshold",
+		gol.DefaultPngThreshold,
+		"Specify the brightness (1-255) at or above which a pixel of a png input is alive. Defaults to 128.")
+
+	flag.BoolVar(
+		&params.OutputRle,
+		"rle",
+		false,
+		"Write an rle file next to each pgm file that is output.")
+
+	flag.BoolVar(
+		&params.OutputLife106,
+		"life106",
+		false,
+		"Write a Life 1.06 (.lif) file next to each pgm file that is output.")
+
+	flag.BoolVar(
+		&params.OutputPng,
+		"png",
+		false,
+		"Write a png file next to each pgm file that is output.")
+
+	flag.BoolVar(
+		&params.OutputCensus,
+		"census",
+		false,
+		"Write a census of the objects in the world, such as blocks and gliders, next to each pgm file that is output.")
+
+	flag.IntVar(
+		&params.PngScale,
+		"pngScale",
+		1,
+		"Specify how many pixels wide each cell is in png output. Defaults to 1.")
+
+	legacyFlips := flag.Bool(
+		"legacyFlips",
+		false,
+		"Send a CellFlipped event for each cell that changes, instead of the default of one CellsFlipped event per turn.")
+
+	params.Security.RegisterFlags()
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
@@ -45,24 +195,134 @@ func main() {
 
 	flag.Parse()
 
+	params.BatchFlips = !*legacyFlips
+	if *metrics != "" {
+		params.ReportTiming = true
+	}
+
+	if *soup {
+		if params.Input != "" {
+			fmt.Println("Only one of -input and -soup can be given.")
+			os.Exit(1)
+		}
+		if *soupSize != "" {
+			if _, err := fmt.Sscanf(*soupSize, "%dx%d", &soupParams.Width, &soupParams.Height); err != nil {
+				fmt.Println("Invalid soup size:", *soupSize)
+				os.Exit(1)
+			}
+		}
+		params.Soup = &soupParams
+	}
+
+	if *regions != "" {
+		_, err := fmt.Sscanf(*regions, "%dx%d", &params.RegionColumns, &params.RegionRows)
+		if err != nil || params.RegionColumns < 1 || params.RegionRows < 1 {
+			fmt.Println("Invalid regions:", *regions)
+			os.Exit(1)
+		}
+	}
+
+	if *record != "" {
+		recording, err := gol.ParseRecording(*record)
+		if err != nil {
+			fmt.Println("Invalid recording:", err)
+			os.Exit(1)
+		}
+		params.Record = &recording
+	}
+
+	if params.ImageWidth == 0 || params.ImageHeight == 0 {
+		width, height, err := gol.InputSize(params)
+		if err != nil {
+			fmt.Println("Error reading input:", err)
+			os.Exit(1)
+		}
+		if params.ImageWidth == 0 {
+			params.ImageWidth = width
+		}
+		if params.ImageHeight == 0 {
+			params.ImageHeight = height
+		}
+	}
+
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
+	// cells toggled in the window while the game is paused
+	edits := make(chan []util.Cell, 10)
+	params.Edits = edits
+
+	// every event goes to the window, and to the event log if there is one
+	bus := gol.NewEventBus()
+	shown := bus.Subscribe(nil, gol.Block, 1000)
+	logDone := make(chan error, 1)
+	if *eventLog != "" {
+		file, err := os.Create(*eventLog)
+		var writer *gol.EventWriter
+		if err == nil {
+			writer, err = gol.NewEventWriter(file, params.ImageWidth, params.ImageHeight)
+		}
+		if err != nil {
+			fmt.Println("Error starting event log:", err)
+			os.Exit(1)
+		}
+		logged := bus.Subscribe(nil, gol.Block, 1000)
+		go func() {
+			err := gol.LogEvents(writer, logged)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			logDone <- err
+		}()
+	} else {
+		close(logDone)
+	}
 
+	metricsDone := make(chan error, 1)
+	if *metrics != "" {
+		file, err := os.Create(*metrics)
+		if err != nil {
+			fmt.Println("Error starting metrics:", err)
+			os.Exit(1)
+		}
+		measured := bus.Subscribe(gol.IsMetric, gol.Block, 1000)
+		go func() {
+			err := gol.LogMetrics(file, measured)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			metricsDone <- err
+		}()
+	} else {
+		close(metricsDone)
+	}
+
+	go bus.Run(events)
 	go gol.Run(params, events, keyPresses)
 	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+		sdl.Run(params, shown, keyPresses, edits)
 	} else {
 		complete := false
 		for !complete {
-			event := <-events
+			event, ok := <-shown
+			if !ok {
+				break
+			}
 			switch event.(type) {
+			case gol.IOFailed, gol.WorkerJoined, gol.WorkerLost, gol.StabilityReached:
+				fmt.Println(event)
 			case gol.FinalTurnComplete:
 				complete = true
 			}
 		}
 	}
+	if err := <-logDone; err != nil {
+		fmt.Println("Error writing event log:", err)
+	}
+	if err := <-metricsDone; err != nil {
+		fmt.Println("Error writing metrics:", err)
+	}
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+)
+
+// main replays an event log written by 'go run . -eventLog FILE' with 'go run ./replay FILE',
+// without needing a broker or servers.
+func main() {
+	runtime.LockOSThread()
+
+	speed := flag.Float64(
+		"speed",
+		1,
+		"Specify how many times faster than it was recorded to replay the run, or 0 for as fast as possible. Defaults to 1.")
+
+	noVis := flag.Bool(
+		"noVis",
+		false,
+		"Disables the SDL window, so the events are printed instead.")
+
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: replay [-speed S] [-noVis] FILE")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Println("Error opening event log:", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	eventLog, err := gol.NewEventReader(file)
+	if err != nil {
+		fmt.Println("Error reading event log:", err)
+		os.Exit(1)
+	}
+
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+	replayDone := make(chan error, 1)
+	go func() {
+		replayDone <- gol.Replay(eventLog, events, keyPresses, *speed)
+	}()
+
+	if !(*noVis) {
+		sdl.Run(gol.Params{ImageWidth: eventLog.Width, ImageHeight: eventLog.Height}, events, keyPresses, nil)
+		// the window closes at the final turn, so let the replay finish anything logged after it
+		for range events {
+		}
+	} else {
+		for event := range events {
+			if len(event.String()) > 0 {
+				fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+			}
+		}
+	}
+	if err := <-replayDone; err != nil {
+		fmt.Println("Error replaying event log:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,102 @@
+package sdl
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// editor toggles the cells clicked and dragged over with the left mouse button while the game is paused.
+// The edits are shown in the window until they are sent, then undone so that the window only shows the cells
+// the broker has flipped, which come back as a CellsEdited event.
+type editor struct {
+	w       *Window
+	toggled []util.Cell        // in the order they were toggled, possibly more than once
+	stroke  map[util.Cell]bool // cells toggled by the drag in progress, which it does not toggle again, nil if none
+	last    util.Cell          // cell the drag in progress was last over
+}
+
+// HandleEvent toggles cells on left clicks and drags, and returns whether the event was one of these.
+func (ed *editor) HandleEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.MouseButtonEvent:
+		if e.Button != sdl.BUTTON_LEFT {
+			return false
+		}
+		if e.Type == sdl.MOUSEBUTTONUP {
+			ed.stroke = nil
+			return true
+		}
+		if c, ok := ed.w.CellAt(e.X, e.Y); ok {
+			ed.stroke = make(map[util.Cell]bool)
+			ed.toggle(c)
+			ed.last = c
+		}
+	case *sdl.MouseMotionEvent:
+		if ed.stroke == nil {
+			return false
+		}
+		c, ok := ed.w.CellAt(e.X, e.Y)
+		if !ok {
+			return true
+		}
+		// fill in the cells between, since a fast drag can skip over several in one motion
+		dx, dy := c.X-ed.last.X, c.Y-ed.last.Y
+		steps := abs(dx)
+		if abs(dy) > steps {
+			steps = abs(dy)
+		}
+		for i := 1; i <= steps; i++ {
+			ed.toggle(util.Cell{X: ed.last.X + (dx*i+sign(dx)*steps/2)/steps, Y: ed.last.Y + (dy*i+sign(dy)*steps/2)/steps})
+		}
+		ed.last = c
+	default:
+		return false
+	}
+	return true
+}
+
+func (ed *editor) toggle(c util.Cell) {
+	if ed.stroke[c] {
+		return
+	}
+	ed.stroke[c] = true
+	ed.toggled = append(ed.toggled, c)
+	ed.w.FlipPixel(c.X, c.Y)
+	ed.w.RenderFrame()
+}
+
+// send undoes the edits shown in the window and sends them as one batch down edits, if there are any.
+// It does not wait for the game to take them, since the game stops reading edits once it has ended,
+// so they are dropped if edits is full.
+func (ed *editor) send(edits chan<- []util.Cell) {
+	ed.stroke = nil
+	if len(ed.toggled) == 0 {
+		return
+	}
+	for _, c := range ed.toggled {
+		ed.w.FlipPixel(c.X, c.Y)
+	}
+	ed.w.RenderFrame()
+	select {
+	case edits <- ed.toggled:
+	default:
+		fmt.Println("Edit dropped: the game is not taking edits")
+	}
+	ed.toggled = nil
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func sign(a int) int {
+	if a < 0 {
+		return -1
+	}
+	return 1
+}
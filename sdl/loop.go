@@ -2,29 +2,49 @@ package sdl
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
+// Run shows the game in a window until the final turn. While the game is paused, cells can be toggled with the
+// left mouse button, and the edits are sent down edits before the next key is passed on, unless edits is nil.
+// edits should be buffered, since the edits are dropped rather than waiting for the game to take them.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, edits chan<- []util.Cell) {
 	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	var timingShown time.Time // TurnTiming is sent every turn, so it is only printed every 2s
+	ed := &editor{w: w}
+	paused := false
+	// press passes a key on to the game, after any edits made while it was paused
+	press := func(key rune) {
+		if edits != nil {
+			ed.send(edits)
+		}
+		keyPresses <- key
+	}
 
 sdlLoop:
 	for {
 		event := w.PollEvent()
-		if event != nil {
+		if event != nil && edits != nil && paused && ed.HandleEvent(event) {
+			event = nil
+		}
+		if event != nil && !w.HandleEvent(event) {
 			switch e := event.(type) {
 			case *sdl.KeyboardEvent:
 				switch e.Keysym.Sym {
 				case sdl.K_p:
-					keyPresses <- 'p'
+					press('p')
 				case sdl.K_s:
-					keyPresses <- 's'
+					press('s')
 				case sdl.K_q:
-					keyPresses <- 'q'
+					press('q')
 				case sdl.K_k:
-					keyPresses <- 'k'
+					press('k')
+				case sdl.K_c:
+					press('c')
 				}
 			}
 		}
@@ -37,11 +57,24 @@ sdlLoop:
 			switch e := event.(type) {
 			case gol.CellFlipped:
 				w.FlipPixel(e.Cell.X, e.Cell.Y)
+			case gol.CellsFlipped:
+				w.FlipPixels(e.Cells)
+			case gol.CellsEdited:
+				w.FlipPixels(e.Cells)
+				w.RenderFrame()
 			case gol.TurnComplete:
 				w.RenderFrame()
 			case gol.FinalTurnComplete:
 				w.Destroy()
 				break sdlLoop
+			case gol.StateChange:
+				paused = e.NewState == gol.Paused
+				fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+			case gol.TurnTiming:
+				if time.Since(timingShown) >= 2*time.Second {
+					timingShown = time.Now()
+					fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+				}
 			default:
 				if len(event.String()) > 0 {
 					fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
@@ -50,6 +83,7 @@ sdlLoop:
 		default:
 			break
 		}
+		w.Refresh()
 	}
 
 }
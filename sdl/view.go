@@ -0,0 +1,154 @@
+package sdl
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const (
+	// smallWorld is the size in screen pixels that worlds smaller than it are scaled up towards when the window opens.
+	smallWorld = 512
+	maxZoom    = 64
+	// zoomStep is how much one notch of the mouse wheel zooms in or out, and +/- zoom by twice as much.
+	zoomStep = 1.25
+	// panStep is the fraction of the window that an arrow key pans by.
+	panStep = 0.1
+)
+
+// view is the part of the world shown in the window: each cell is zoom screen pixels across,
+// and the point x, y of the world is at the top left corner of the window.
+type view struct {
+	zoom float64
+	x, y float64
+}
+
+// initialZoom returns the zoom a window opens at: an exact multiple for small worlds, so that every cell is the
+// same size, or a fraction for worlds too big to fit on the display.
+func initialZoom(width, height int32) float64 {
+	bounds, err := sdl.GetDisplayUsableBounds(0)
+	if err != nil {
+		bounds = sdl.Rect{W: smallWorld * 2, H: smallWorld * 2}
+	}
+	// leave room for the window's decorations
+	fit := math.Min(0.9*float64(bounds.W)/float64(width), 0.9*float64(bounds.H)/float64(height))
+	if fit < 1 {
+		return fit
+	}
+	larger := math.Max(float64(width), float64(height))
+	return math.Max(1, math.Min(math.Floor(fit), math.Floor(smallWorld/larger)))
+}
+
+// fitZoom returns the zoom that fits the whole world into the window.
+func (w *Window) fitZoom() float64 {
+	return math.Min(float64(w.screenWidth)/float64(w.Width), float64(w.screenHeight)/float64(w.Height))
+}
+
+// Fit zooms the view so that the whole world fits into the window, in the middle of it.
+func (w *Window) Fit() {
+	w.view.zoom = w.fitZoom()
+	w.view.x = (float64(w.Width) - float64(w.screenWidth)/w.view.zoom) / 2
+	w.view.y = (float64(w.Height) - float64(w.screenHeight)/w.view.zoom) / 2
+	w.moved = true
+}
+
+// Zoom multiplies the zoom of the view by factor, keeping the point of the world under the screen pixel x, y still.
+// The view zooms out no further than half the size that fits the window.
+func (w *Window) Zoom(factor float64, x, y int32) {
+	zoom := math.Max(w.fitZoom()/2, math.Min(maxZoom, w.view.zoom*factor))
+	w.view.x += float64(x)/w.view.zoom - float64(x)/zoom
+	w.view.y += float64(y)/w.view.zoom - float64(y)/zoom
+	w.view.zoom = zoom
+	w.Pan(0, 0)
+}
+
+// Pan moves the view by dx, dy screen pixels, keeping at least half of the window over the world.
+func (w *Window) Pan(dx, dy int32) {
+	halfWidth := float64(w.screenWidth) / w.view.zoom / 2
+	halfHeight := float64(w.screenHeight) / w.view.zoom / 2
+	w.view.x = math.Max(-halfWidth, math.Min(float64(w.Width)-halfWidth, w.view.x+float64(dx)/w.view.zoom))
+	w.view.y = math.Max(-halfHeight, math.Min(float64(w.Height)-halfHeight, w.view.y+float64(dy)/w.view.zoom))
+	w.moved = true
+}
+
+// CellAt returns the cell of the world under the screen pixel x, y, and whether there is one there.
+func (w *Window) CellAt(x, y int32) (util.Cell, bool) {
+	c := util.Cell{
+		X: int(math.Floor(w.view.x + float64(x)/w.view.zoom)),
+		Y: int(math.Floor(w.view.y + float64(y)/w.view.zoom)),
+	}
+	return c, c.X >= 0 && c.Y >= 0 && c.X < int(w.Width) && c.Y < int(w.Height)
+}
+
+// visible returns the part of the world that is in view, and where it is drawn in the window.
+// Whole cells are copied, so that each cell is drawn the same size however far the view is panned.
+func (w *Window) visible() (src, dst sdl.Rect) {
+	clamp := func(i float64, max int32) int32 {
+		return int32(math.Max(0, math.Min(float64(max), i)))
+	}
+	x0 := clamp(math.Floor(w.view.x), w.Width)
+	y0 := clamp(math.Floor(w.view.y), w.Height)
+	x1 := clamp(math.Ceil(w.view.x+float64(w.screenWidth)/w.view.zoom), w.Width)
+	y1 := clamp(math.Ceil(w.view.y+float64(w.screenHeight)/w.view.zoom), w.Height)
+	src = sdl.Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+
+	screen := func(i int32, at float64) int32 {
+		return int32(math.Round((float64(i) - at) * w.view.zoom))
+	}
+	dst.X, dst.Y = screen(x0, w.view.x), screen(y0, w.view.y)
+	dst.W, dst.H = screen(x1, w.view.x)-dst.X, screen(y1, w.view.y)-dst.Y
+	return src, dst
+}
+
+// HandleEvent zooms and pans the view on the mouse wheel, dragging, the arrow keys, + and -, and f to fit the
+// world to the window, and keeps the view in step with the size of the window.
+// It returns whether the event was one of these, so that other keys can be handled by the caller.
+func (w *Window) HandleEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.MouseWheelEvent:
+		notches := e.Y
+		if e.Direction == sdl.MOUSEWHEEL_FLIPPED {
+			notches = -notches
+		}
+		x, y, _ := sdl.GetMouseState()
+		w.Zoom(math.Pow(zoomStep, float64(notches)), x, y)
+	case *sdl.MouseButtonEvent:
+		w.dragging = e.Type == sdl.MOUSEBUTTONDOWN
+	case *sdl.MouseMotionEvent:
+		if !w.dragging {
+			return false
+		}
+		w.Pan(-e.XRel, -e.YRel)
+	case *sdl.WindowEvent:
+		if e.Event != sdl.WINDOWEVENT_SIZE_CHANGED {
+			return false
+		}
+		w.screenWidth, w.screenHeight = e.Data1, e.Data2
+		w.Pan(0, 0)
+	case *sdl.KeyboardEvent:
+		stepX := int32(panStep * float64(w.screenWidth))
+		stepY := int32(panStep * float64(w.screenHeight))
+		switch e.Keysym.Sym {
+		case sdl.K_LEFT:
+			w.Pan(-stepX, 0)
+		case sdl.K_RIGHT:
+			w.Pan(stepX, 0)
+		case sdl.K_UP:
+			w.Pan(0, -stepY)
+		case sdl.K_DOWN:
+			w.Pan(0, stepY)
+		case sdl.K_EQUALS, sdl.K_KP_PLUS:
+			w.Zoom(zoomStep*zoomStep, w.screenWidth/2, w.screenHeight/2)
+		case sdl.K_MINUS, sdl.K_KP_MINUS:
+			w.Zoom(1/(zoomStep*zoomStep), w.screenWidth/2, w.screenHeight/2)
+		case sdl.K_f:
+			w.Fit()
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
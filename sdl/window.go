@@ -2,6 +2,7 @@ package sdl
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/util"
@@ -13,33 +14,57 @@ type Window struct {
 	renderer      *sdl.Renderer
 	texture       *sdl.Texture
 	pixels        []byte
+
+	screenWidth, screenHeight int32 // size of the window in screen pixels
+	view                      view
+	dragging                  bool
+	stale                     bool      // the pixels have changed since the texture was last updated
+	moved                     bool      // the view has changed since the window was last drawn
+	drawn                     time.Time // when the window was last drawn
 }
 
+// frameInterval is the shortest time between drawing frames, so that fast turns do not all have to be drawn.
+const frameInterval = time.Second / 60
+
 func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
+	switch e.GetType() {
+	case sdl.KEYDOWN, sdl.QUIT, sdl.MOUSEWHEEL, sdl.MOUSEBUTTONDOWN, sdl.MOUSEBUTTONUP, sdl.MOUSEMOTION, sdl.WINDOWEVENT:
+		return true
+	}
+	return false
 }
 
 func NewWindow(width, height int32) *Window {
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
+	zoom := initialZoom(width, height)
+	screenWidth, screenHeight := int32(float64(width)*zoom), int32(float64(height)*zoom)
+	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED,
+		screenWidth, screenHeight, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	util.Check(err)
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
 	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
+	// scaling up keeps cells square, scaling down blends them so that lone cells do not vanish
+	if zoom >= 1 {
+		sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
+	} else {
+		sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
+	}
 	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
 	util.Check(err)
 
 	sdl.SetEventFilterFunc(filterEvent, nil)
 	return &Window{
-		width,
-		height,
-		window,
-		renderer,
-		texture,
-		make([]byte, width*height*4),
+		Width:        width,
+		Height:       height,
+		window:       window,
+		renderer:     renderer,
+		texture:      texture,
+		pixels:       make([]byte, width*height*4),
+		screenWidth:  screenWidth,
+		screenHeight: screenHeight,
+		view:         view{zoom: zoom},
+		moved:        true,
 	}
 }
 
@@ -53,14 +78,36 @@ func (w *Window) Destroy() {
 	sdl.Quit()
 }
 
+// RenderFrame draws the pixels into the window, or leaves them to the next Refresh if a frame was drawn too
+// recently.
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
+	w.stale = true
+	w.Refresh()
+}
+
+// Refresh draws the window if its pixels or view have changed, and if frameInterval has passed since it was last
+// drawn. It is cheap enough to call on every pass of an event loop.
+func (w *Window) Refresh() {
+	if !w.stale && !w.moved || time.Since(w.drawn) < frameInterval {
+		return
+	}
+	if w.stale {
+		err := w.texture.Update(nil, w.pixels, int(w.Width*4))
+		util.Check(err)
+	}
+	// the space around the world is grey, to tell it apart from dead cells
+	err := w.renderer.SetDrawColor(0x30, 0x30, 0x30, 0xFF)
 	util.Check(err)
 	err = w.renderer.Clear()
 	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
-	util.Check(err)
+	src, dst := w.visible()
+	if src.W > 0 && src.H > 0 {
+		err = w.renderer.Copy(w.texture, &src, &dst)
+		util.Check(err)
+	}
 	w.renderer.Present()
+	w.stale, w.moved = false, false
+	w.drawn = time.Now()
 }
 
 func (w *Window) PollEvent() sdl.Event {
@@ -87,6 +134,13 @@ func (w *Window) FlipPixel(x, y int) {
 	w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
 }
 
+// FlipPixels flips every pixel of a CellsFlipped event.
+func (w *Window) FlipPixels(cells []util.Cell) {
+	for _, c := range cells {
+		w.FlipPixel(c.X, c.Y)
+	}
+}
+
 func (w *Window) CountPixels() int {
 	count := 0
 	for i := 0; i < int(w.Width) * int(w.Height) * 4; i += 4 {
@@ -3,13 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
-	"net"
 	"net/rpc"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/stubs"
 )
 
-var closeServerChan chan struct{}
+var (
+	closeServerChan chan struct{}
+	security        stubs.Security
+)
 
 type Server struct{}
 
@@ -42,6 +45,7 @@ func countNonZeroValues(arr []int) int {
 }
 
 func (s *Server) ReturnNextState(req stubs.NextStateRequest, res *stubs.NextStateResponse) (err error) {
+	began := time.Now()
 
 	// split heights as evenly as possible
 	heights := calcHeights(req.EndY-req.StartY, req.Threads)
@@ -72,6 +76,7 @@ func (s *Server) ReturnNextState(req stubs.NextStateRequest, res *stubs.NextStat
 		newWorld = append(newWorld, <-workers[i]...)
 	}
 	res.World = newWorld
+	res.Elapsed = time.Since(began)
 	return
 }
 
@@ -137,11 +142,12 @@ func (s *Server) CloseServer(req stubs.CloseServerRequest, res *stubs.CloseServe
 func main() {
 	var pAddr string
 	flag.StringVar(&pAddr, "port", "8050", "set the port that the server will listen on")
+	security.RegisterFlags()
 	flag.Parse()
 	fmt.Println(pAddr)
 
 	rpc.Register(&Server{})
-	listener, err := net.Listen("tcp", ":"+pAddr)
+	listener, err := security.Listen(":" + pAddr)
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -149,7 +155,7 @@ func main() {
 	go func() {
 		fmt.Println("Server listening on", listener.Addr())
 		defer listener.Close()
-		rpc.Accept(listener)
+		security.Accept(listener)
 	}()
 
 	<-closeServerChan
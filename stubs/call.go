@@ -0,0 +1,37 @@
+package stubs
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"time"
+)
+
+// ErrTimeout is returned by CallTimeout when no reply arrives before the deadline.
+var ErrTimeout = errors.New("rpc call timed out")
+
+// ErrCancelled is returned by CallTimeout when the cancel channel is closed before a reply arrives.
+var ErrCancelled = errors.New("rpc call cancelled")
+
+// CallTimeout behaves like client.Call but gives up once timeout has elapsed or cancel is closed.
+// A timeout of zero or less never expires and a nil cancel channel is never closed.
+// If the call is abandoned, reply may still be written to later, so it must not be reused.
+func CallTimeout(client *rpc.Client, serviceMethod string, args interface{}, reply interface{}, timeout time.Duration, cancel <-chan struct{}) error {
+	call := client.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-expired:
+		return fmt.Errorf("%v after %v: %w", serviceMethod, timeout, ErrTimeout)
+	case <-cancel:
+		return fmt.Errorf("%v: %w", serviceMethod, ErrCancelled)
+	}
+}
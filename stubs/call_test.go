@@ -0,0 +1,67 @@
+package stubs
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+// stallingBroker stands in for the broker, stalling calls to Pause until stalled is closed.
+type stallingBroker struct {
+	stalled chan struct{}
+}
+
+func (b *stallingBroker) Pause(req PauseRequest, res *PauseResponse) (err error) {
+	<-b.stalled
+	return
+}
+
+func (b *stallingBroker) AliveCellsCount(req AliveCellsCountRequest, res *AliveCellsCountResponse) (err error) {
+	res.CellsCount = 42
+	return
+}
+
+// TestCallTimeout checks that a call to a method that stalls gives up once its timeout has passed or its cancel
+// channel is closed, and that a call that replies in time is unaffected.
+func TestCallTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", &stallingBroker{stalled: stalled}); err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+	defer close(stalled)
+
+	start := time.Now()
+	err := CallTimeout(client, Pause, PauseRequest{}, new(PauseResponse), 50*time.Millisecond, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the call to give up after 50ms, it took %v", elapsed)
+	}
+
+	cancel := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(cancel)
+	}()
+	start = time.Now()
+	err = CallTimeout(client, Pause, PauseRequest{}, new(PauseResponse), 0, cancel)
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("expected the call to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the call to be cancelled after 50ms, it took %v", elapsed)
+	}
+
+	res := new(AliveCellsCountResponse)
+	if err := CallTimeout(client, AliveCellsCount, AliveCellsCountRequest{}, res, time.Second, nil); err != nil || res.CellsCount != 42 {
+		t.Errorf("expected a reply in time, got %v, %v", res.CellsCount, err)
+	}
+}
@@ -0,0 +1,120 @@
+package stubs
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Flate is the name of the flate world encoding, the only compression supported so far.
+const Flate = "flate"
+
+// PackedWorld is a world flattened into a single slice and, if that made it smaller, compressed.
+// It replaces the World field of a request or response once both ends have agreed on an encoding.
+type PackedWorld struct {
+	Encoding string // "" if Data holds the raw cells
+	Height   int
+	Width    int
+	Data     []byte
+}
+
+// ChooseEncoding returns the first of the offered encodings that is also supported, or "" if there is none.
+func ChooseEncoding(offered, supported []string) string {
+	for _, o := range offered {
+		for _, s := range supported {
+			if o == s {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// PackWorld flattens world and compresses it with encoding, keeping the raw cells if compressing does not help.
+func PackWorld(world [][]byte, encoding string) (PackedWorld, error) {
+	packed := PackedWorld{Height: len(world)}
+	if packed.Height > 0 {
+		packed.Width = len(world[0])
+	}
+	raw := make([]byte, 0, packed.Height*packed.Width)
+	for _, row := range world {
+		raw = append(raw, row...)
+	}
+	packed.Data = raw
+
+	switch encoding {
+	case "":
+		return packed, nil
+	case Flate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return packed, err
+		}
+		if _, err = w.Write(raw); err != nil {
+			return packed, err
+		}
+		if err = w.Close(); err != nil {
+			return packed, err
+		}
+		if buf.Len() < len(raw) {
+			packed.Encoding = Flate
+			packed.Data = buf.Bytes()
+		}
+		return packed, nil
+	}
+	return packed, fmt.Errorf("unknown world encoding %q", encoding)
+}
+
+// UnpackWorld returns world if it was sent as is, otherwise it unpacks packed.
+func UnpackWorld(world [][]byte, packed PackedWorld) ([][]byte, error) {
+	if world != nil || packed.Data == nil {
+		return world, nil
+	}
+	return packed.Unpack()
+}
+
+// Unpack restores the world held by p.
+func (p PackedWorld) Unpack() ([][]byte, error) {
+	raw := p.Data
+	switch p.Encoding {
+	case "":
+	case Flate:
+		r := flate.NewReader(bytes.NewReader(p.Data))
+		defer r.Close()
+		// Reading one byte past the world is enough to tell it is too long, without inflating all of it.
+		var err error
+		if raw, err = ioutil.ReadAll(io.LimitReader(r, int64(p.Height*p.Width)+1)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown world encoding %q", p.Encoding)
+	}
+	if len(raw) != p.Height*p.Width {
+		return nil, fmt.Errorf("packed world has %v cells, expected %vx%v", len(raw), p.Width, p.Height)
+	}
+
+	world := make([][]byte, p.Height)
+	for y := range world {
+		world[y] = raw[y*p.Width : (y+1)*p.Width : (y+1)*p.Width]
+	}
+	return world, nil
+}
+
+// Ratio is how many times smaller the packed data is than the raw cells.
+func (p PackedWorld) Ratio() float64 {
+	if len(p.Data) == 0 {
+		return 1
+	}
+	return float64(p.Height*p.Width) / float64(len(p.Data))
+}
+
+// String describes the size of the packed world, for logging.
+func (p PackedWorld) String() string {
+	if p.Encoding == "" {
+		return fmt.Sprintf("%v bytes uncompressed", len(p.Data))
+	}
+	return fmt.Sprintf("%v bytes compressed with %v to %v (%.1fx)", p.Height*p.Width, p.Encoding, len(p.Data), p.Ratio())
+}
@@ -0,0 +1,90 @@
+package stubs
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestChooseEncoding checks that the first encoding offered that is also supported is chosen.
+func TestChooseEncoding(t *testing.T) {
+	tests := []struct {
+		name               string
+		offered, supported []string
+		expected           string
+	}{
+		{"agreed", []string{Flate}, []string{Flate}, Flate},
+		{"preference of the offer", []string{"zstd", Flate}, []string{Flate, "zstd"}, "zstd"},
+		{"none supported", []string{"zstd"}, []string{Flate}, ""},
+		{"none offered", nil, []string{Flate}, ""},
+		{"compression disabled", []string{Flate}, nil, ""},
+	}
+	for _, test := range tests {
+		if chosen := ChooseEncoding(test.offered, test.supported); chosen != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.name, test.expected, chosen)
+		}
+	}
+}
+
+// TestPackWorld checks that worlds come back unchanged from each encoding, and that flate is only used when
+// it makes the world smaller.
+func TestPackWorld(t *testing.T) {
+	sparse := make([][]byte, 64)
+	noisy := make([][]byte, 64)
+	random := rand.New(rand.NewSource(1))
+	for y := range sparse {
+		sparse[y] = make([]byte, 32)
+		noisy[y] = make([]byte, 32)
+		random.Read(noisy[y])
+	}
+	sparse[3][5] = 255
+	sparse[63][31] = 255
+
+	tests := []struct {
+		name     string
+		world    [][]byte
+		encoding string
+		packedAs string
+	}{
+		{"raw", sparse, "", ""},
+		{"flate", sparse, Flate, Flate},
+		{"flate that does not help", noisy, Flate, ""},
+	}
+	for _, test := range tests {
+		packed, err := PackWorld(test.world, test.encoding)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if packed.Encoding != test.packedAs {
+			t.Errorf("%v: expected the world to be packed as %q, got %q", test.name, test.packedAs, packed.Encoding)
+		}
+		world, err := UnpackWorld(nil, packed)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(world, test.world) {
+			t.Errorf("%v: the world changed on its way through packing", test.name)
+		}
+	}
+
+	if _, err := PackWorld(sparse, "zstd"); err == nil {
+		t.Error("expected an unknown encoding to be refused")
+	}
+	if _, err := (PackedWorld{Encoding: "zstd", Data: []byte{0}}).Unpack(); err == nil {
+		t.Error("expected an unknown encoding to be refused when unpacking")
+	}
+	if _, err := (PackedWorld{Height: 2, Width: 2, Data: []byte{0}}).Unpack(); err == nil {
+		t.Error("expected a world of the wrong size to be refused")
+	}
+	long, err := PackWorld(sparse, Flate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long.Height, long.Width = 2, 2
+	if _, err := long.Unpack(); err == nil {
+		t.Error("expected a compressed world longer than its size to be refused")
+	}
+	if world, _ := UnpackWorld(sparse, PackedWorld{}); !reflect.DeepEqual(world, sparse) {
+		t.Error("expected a world sent as is to be returned as is")
+	}
+}
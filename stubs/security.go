@@ -0,0 +1,343 @@
+package stubs
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"strings"
+	"time"
+)
+
+// Role is the level of access granted to a connection by the token it presents.
+// A higher role may call everything a lower role may.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleController
+	RoleBroker
+	RoleAdmin
+)
+
+// requiredRoles lists the role needed to call each method. Methods that are not listed cannot be called at all,
+// so every method a component registers must have an entry.
+var requiredRoles = map[string]Role{
+	ReadyToDial:      RoleController,
+	RunGame:          RoleController,
+	AliveCellsCount:  RoleController,
+	Screenshot:       RoleController,
+	Quit:             RoleController,
+	Pause:            RoleController,
+	Restart:          RoleController,
+	Step:             RoleController,
+	Census:           RoleController,
+	RegionStats:      RoleController,
+	EditCells:        RoleController,
+	NextState:        RoleBroker,
+	SendWorldState:   RoleBroker,
+	SendWorkerChange: RoleBroker,
+	CloseBroker:      RoleAdmin,
+	CloseServer:      RoleAdmin,
+}
+
+// ErrPermissionDenied is returned to a client that calls a method its token does not allow.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrUnauthenticated is returned by Authorize for a token that is missing or not known, rather than one whose
+// role is too low.
+var ErrUnauthenticated = errors.New("missing or unknown token")
+
+const handshakeTimeout = 5 * time.Second
+
+// Security describes how a component protects its listener and authenticates when dialling.
+// The zero value disables both TLS and tokens, which is how everything runs locally.
+type Security struct {
+	CertFile   string // certificate presented by this component, enables TLS on its listener and when dialling
+	KeyFile    string // private key for CertFile
+	CAFile     string // CA used to verify peers, if empty the system roots are used, enables TLS when dialling
+	TLS        bool   // whether to dial over TLS even without a certificate or CA file
+	ClientAuth bool   // whether the listener requires a certificate from clients, verified against CAFile
+
+	Secret          string // shared token accepted with the admin role and presented for every role
+	ControllerToken string
+	BrokerToken     string
+	AdminToken      string
+}
+
+// RegisterFlags adds the TLS and token flags to the default command line.
+func (s *Security) RegisterFlags() {
+	flag.StringVar(&s.CertFile, "cert", "", "set the TLS certificate file (enables TLS for listening and dialling)")
+	flag.StringVar(&s.KeyFile, "key", "", "set the TLS private key file")
+	flag.StringVar(&s.CAFile, "ca", "", "set the CA file used to verify peers (enables TLS for dialling)")
+	flag.BoolVar(&s.TLS, "tls", false, "dial over TLS, verifying peers against the system roots unless -ca is set")
+	flag.BoolVar(&s.ClientAuth, "clientAuth", false, "require a certificate from clients, verified against -ca (mutual TLS)")
+	flag.StringVar(&s.Secret, "secret", "", "set a token shared by every component")
+	flag.StringVar(&s.ControllerToken, "controllerToken", "", "set the token for the controller role")
+	flag.StringVar(&s.BrokerToken, "brokerToken", "", "set the token for the broker role")
+	flag.StringVar(&s.AdminToken, "adminToken", "", "set the token for the admin role (needed to shut down)")
+}
+
+// token returns the token to present when acting as role.
+func (s Security) token(role Role) string {
+	var t string
+	switch role {
+	case RoleController:
+		t = s.ControllerToken
+	case RoleBroker:
+		t = s.BrokerToken
+	case RoleAdmin:
+		t = s.AdminToken
+	}
+	if t == "" {
+		t = s.Secret
+	}
+	return t
+}
+
+// roleOf returns the role granted to a connection presenting token.
+func (s Security) roleOf(token string) Role {
+	if s.Secret == "" && s.ControllerToken == "" && s.BrokerToken == "" && s.AdminToken == "" {
+		return RoleAdmin // authentication is disabled
+	}
+	if token == "" {
+		return RoleNone
+	}
+	// every token is compared, in constant time, so that how long this takes does not give any of them away
+	role := RoleNone
+	for _, known := range []struct {
+		token string
+		role  Role
+	}{
+		{s.AdminToken, RoleAdmin},
+		{s.Secret, RoleAdmin},
+		{s.BrokerToken, RoleBroker},
+		{s.ControllerToken, RoleController},
+	} {
+		if known.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(known.token)) == 1 && known.role > role {
+			role = known.role
+		}
+	}
+	return role
+}
+
+// Authorize checks that token grants the role needed to call serviceMethod.
+// It lets listeners other than rpc, such as the broker's HTTP API, apply the same rules.
+func (s Security) Authorize(token, serviceMethod string) error {
+	role := s.roleOf(token)
+	if role == RoleNone {
+		return fmt.Errorf("%v: %w", serviceMethod, ErrUnauthenticated)
+	}
+	if !allowed(role, serviceMethod) {
+		return fmt.Errorf("%v: %w", serviceMethod, ErrPermissionDenied)
+	}
+	return nil
+}
+
+func allowed(role Role, serviceMethod string) bool {
+	required, ok := requiredRoles[serviceMethod]
+	return ok && role >= required
+}
+
+// dialsTLS reports whether Dial connects over TLS. Listen only does if there is a certificate to present.
+func (s Security) dialsTLS() bool {
+	return s.TLS || s.CertFile != "" || s.CAFile != ""
+}
+
+func (s Security) tlsConfig() (*tls.Config, error) {
+	config := new(tls.Config)
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if s.CAFile != "" {
+		pem, err := ioutil.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %v", s.CAFile)
+		}
+		config.RootCAs = pool
+		config.ClientCAs = pool
+	}
+	if s.ClientAuth {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Listen starts listening on addr, over TLS if a certificate has been configured.
+func (s Security) Listen(addr string) (net.Listener, error) {
+	if s.CertFile == "" {
+		if s.ClientAuth {
+			return nil, errors.New("client certificates can only be required with a certificate to listen with")
+		}
+		return net.Listen("tcp", addr)
+	}
+	config, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, config)
+}
+
+// Dial connects to the rpc server at addr and authenticates as role.
+func (s Security) Dial(addr string, role Role) (*rpc.Client, error) {
+	var conn net.Conn
+	var err error
+	if s.dialsTLS() {
+		var config *tls.Config
+		config, err = s.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		conn, err = tls.Dial("tcp", addr, config)
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	_, err = fmt.Fprintf(conn, "GOL %v\n", s.token(role))
+	if err == nil {
+		var reply string
+		reply, err = readLine(conn)
+		if err == nil && reply != "OK" {
+			err = fmt.Errorf("%v: %w", addr, ErrPermissionDenied)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return rpc.NewClient(conn), nil
+}
+
+// Accept serves rpc requests on the default server for each connection that presents a known token.
+// It replaces rpc.Accept and, like it, only returns once the listener has been closed.
+func (s Security) Accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s Security) serveConn(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	line, err := readLine(conn)
+	if err != nil || !strings.HasPrefix(line, "GOL ") {
+		conn.Close()
+		return
+	}
+	role := s.roleOf(strings.TrimPrefix(line, "GOL "))
+	if role == RoleNone {
+		fmt.Fprintln(conn, "DENIED")
+		conn.Close()
+		return
+	}
+	if _, err = fmt.Fprintln(conn, "OK"); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	rpc.ServeCodec(newAuthServerCodec(conn, role))
+}
+
+// readLine reads up to a newline one byte at a time, so nothing belonging to the rpc stream is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 1024 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", errors.New("handshake line too long")
+}
+
+// authServerCodec is the gob codec used by net/rpc, except that it refuses methods the connection's role does not allow.
+type authServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	role   Role
+	method string
+	closed bool
+}
+
+func newAuthServerCodec(conn io.ReadWriteCloser, role Role) *authServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &authServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+		role:   role,
+	}
+}
+
+func (c *authServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.dec.Decode(r)
+	c.method = r.ServiceMethod
+	return err
+}
+
+// ReadRequestBody discards the body of a refused request and returns an error, which rpc sends back to the caller.
+func (c *authServerCodec) ReadRequestBody(body interface{}) error {
+	if !allowed(c.role, c.method) {
+		var discard interface{} // gob skips a value decoded into nil
+		if err := c.dec.Decode(discard); err != nil {
+			return err
+		}
+		return fmt.Errorf("%v: %w", c.method, ErrPermissionDenied)
+	}
+	return c.dec.Decode(body)
+}
+
+func (c *authServerCodec) WriteResponse(r *rpc.Response, body interface{}) (err error) {
+	if err = c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return
+	}
+	if err = c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *authServerCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
@@ -0,0 +1,223 @@
+package stubs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Broker stands in for the real broker so that the method names match requiredRoles.
+type Broker struct{}
+
+func (b *Broker) AliveCellsCount(req AliveCellsCountRequest, res *AliveCellsCountResponse) (err error) {
+	res.CellsCount = 42
+	return
+}
+
+func (b *Broker) CloseBroker(req CloseBrokerRequest, res *CloseBrokerResponse) (err error) {
+	return
+}
+
+var registerTestBroker sync.Once
+
+// TestSecurityRoles checks that tokens are required and that shutdown needs the admin role.
+func TestSecurityRoles(t *testing.T) {
+	security := Security{ControllerToken: "c", AdminToken: "a"}
+	registerTestBroker.Do(func() { rpc.Register(&Broker{}) })
+	listener, err := security.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go security.Accept(listener)
+	addr := listener.Addr().String()
+
+	if _, err := (Security{ControllerToken: "wrong"}).Dial(addr, RoleController); err == nil {
+		t.Fatal("dial with an unknown token succeeded")
+	}
+
+	client, err := security.Dial(addr, RoleController)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	aliveRes := new(AliveCellsCountResponse)
+	if err := client.Call(AliveCellsCount, AliveCellsCountRequest{}, aliveRes); err != nil || aliveRes.CellsCount != 42 {
+		t.Fatalf("AliveCellsCount as controller: %v, %v", aliveRes.CellsCount, err)
+	}
+	err = client.Call(CloseBroker, CloseBrokerRequest{}, new(CloseBrokerResponse))
+	if err == nil || !strings.Contains(err.Error(), ErrPermissionDenied.Error()) {
+		t.Fatalf("CloseBroker as controller: expected permission denied, got %v", err)
+	}
+
+	// the connection must still be usable after a refused call
+	if err := client.Call(AliveCellsCount, AliveCellsCountRequest{}, new(AliveCellsCountResponse)); err != nil {
+		t.Fatalf("AliveCellsCount after refusal: %v", err)
+	}
+
+	admin, err := security.Dial(addr, RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if err := admin.Call(CloseBroker, CloseBrokerRequest{}, new(CloseBrokerResponse)); err != nil {
+		t.Fatalf("CloseBroker as admin: %v", err)
+	}
+}
+
+// TestRoleOf checks that each token is granted its role, the highest if it is configured for more than one,
+// and that tokens that are not configured grant nothing.
+func TestRoleOf(t *testing.T) {
+	security := Security{ControllerToken: "c", BrokerToken: "b", AdminToken: "a", Secret: "s"}
+	tests := []struct {
+		security Security
+		token    string
+		role     Role
+	}{
+		{security, "c", RoleController},
+		{security, "b", RoleBroker},
+		{security, "a", RoleAdmin},
+		{security, "s", RoleAdmin},
+		{security, "x", RoleNone},
+		{security, "", RoleNone},
+		{Security{ControllerToken: "t", BrokerToken: "t"}, "t", RoleBroker},
+		{Security{ControllerToken: "c"}, "", RoleNone},
+		{Security{}, "", RoleAdmin},
+	}
+	for _, test := range tests {
+		if role := test.security.roleOf(test.token); role != test.role {
+			t.Errorf("%+v: expected %q to have role %v, got %v", test.security, test.token, test.role, role)
+		}
+	}
+}
+
+// TestRequiredRoles checks that every method named in stubs.go has a required role, since methods without one
+// are refused, and that a method that is not listed is refused even to the admin role.
+func TestRequiredRoles(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "stubs.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := 0
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for _, value := range spec.(*ast.ValueSpec).Values {
+				lit, ok := value.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				method, _ := strconv.Unquote(lit.Value)
+				methods++
+				if _, ok := requiredRoles[method]; !ok {
+					t.Errorf("%v has no entry in requiredRoles", method)
+				}
+			}
+		}
+	}
+	if methods == 0 {
+		t.Fatal("found no methods in stubs.go")
+	}
+	if allowed(RoleAdmin, "Broker.Unknown") {
+		t.Error("expected a method missing from requiredRoles to be refused")
+	}
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 that is also its own CA, and its key,
+// returning the names of the files.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gol test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestTLS checks that a client can verify a listener against a CA without a certificate of its own,
+// unless the listener asks for client certificates.
+func TestTLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	registerTestBroker.Do(func() { rpc.Register(&Broker{}) })
+
+	tests := []struct {
+		name     string
+		listener Security
+		client   Security
+		ok       bool
+	}{
+		{"CA only", Security{CertFile: certFile, KeyFile: keyFile}, Security{CAFile: certFile}, true},
+		{"no TLS", Security{CertFile: certFile, KeyFile: keyFile}, Security{}, false},
+		{"system roots", Security{CertFile: certFile, KeyFile: keyFile}, Security{TLS: true}, false},
+		{"no client certificate", Security{CertFile: certFile, KeyFile: keyFile, CAFile: certFile, ClientAuth: true}, Security{CAFile: certFile}, false},
+		{"client certificate", Security{CertFile: certFile, KeyFile: keyFile, CAFile: certFile, ClientAuth: true}, Security{CertFile: certFile, KeyFile: keyFile, CAFile: certFile}, true},
+	}
+	for _, test := range tests {
+		listener, err := test.listener.Listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go test.listener.Accept(listener)
+		client, err := test.client.Dial(listener.Addr().String(), RoleController)
+		if err == nil {
+			err = client.Call(AliveCellsCount, AliveCellsCountRequest{}, new(AliveCellsCountResponse))
+			client.Close()
+		}
+		listener.Close()
+		if test.ok && err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("%v: expected the connection to be refused", test.name)
+		}
+	}
+
+	if _, err := (Security{ClientAuth: true}).Listen("127.0.0.1:0"); err == nil {
+		t.Error("expected client certificates to need a certificate to listen with")
+	}
+}
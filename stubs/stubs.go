@@ -1,47 +1,92 @@
 package stubs
-import "uk.ac.bris.cs/gameoflife/util"
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
 
 var (
-	ReadyToDial     = "Broker.ReadyToDial"
-	RunGame         = "Broker.RunGame"
-	AliveCellsCount = "Broker.AliveCellsCount"
-	Screenshot      = "Broker.Screenshot"
-	Quit            = "Broker.Quit"
-	CloseBroker     = "Broker.CloseBroker"
-	Pause           = "Broker.Pause"
-	Restart         = "Broker.Restart"
-	NextState       = "Server.ReturnNextState"
-	CloseServer     = "Server.CloseServer"
-	SendWorldState  = "Controller.SendWorldState"
+	ReadyToDial      = "Broker.ReadyToDial"
+	RunGame          = "Broker.RunGame"
+	AliveCellsCount  = "Broker.AliveCellsCount"
+	Screenshot       = "Broker.Screenshot"
+	Quit             = "Broker.Quit"
+	CloseBroker      = "Broker.CloseBroker"
+	Pause            = "Broker.Pause"
+	Restart          = "Broker.Restart"
+	Step             = "Broker.Step"
+	Census           = "Broker.Census"
+	RegionStats      = "Broker.RegionStats"
+	EditCells        = "Broker.EditCells"
+	NextState        = "Server.ReturnNextState"
+	CloseServer      = "Server.CloseServer"
+	SendWorldState   = "Controller.SendWorldState"
+	SendWorkerChange = "Controller.SendWorkerChange"
 )
 
 type ReadyToDialRequest struct {
-	S    string
-	Port string
+	S           string
+	Port        string
+	Compression []string // world encodings the controller accepts, most preferred first
 }
 
 type ReadyToDialResponse struct {
-	S string
+	S           string
+	Compression string // world encoding chosen for this controller, "" for none
 }
 
 type SendWorldStateRequest struct {
 	CellsFlipped   []util.Cell
 	CompletedTurns int
 	CellsCount     int
+	Timing         TurnTiming
+	Period         int // set on the turn the world first repeats, to the number of turns it repeats after
+	FirstTurn      int // turn from which the world has repeated, when Period is set
 }
 
 type SendWorldStateResponse struct{}
 
+// TurnTiming breaks down where the broker spent the time taken by a turn.
+type TurnTiming struct {
+	Compute  time.Duration // time the slowest server spent computing its slice
+	Network  time.Duration // time waiting for the servers other than computing, such as sending the world
+	Assembly time.Duration // time putting the slices together and working out the cells that flipped
+	Interval time.Duration // time since the previous turn finished, or since the game started
+}
+
+// WorkerChangeRequest tells the controller that a server has joined the game or been lost from it.
+type WorkerChangeRequest struct {
+	CompletedTurns int
+	Address        string
+	StartY         int // first row of the slice assigned to the server
+	EndY           int // row after the last row of the slice
+	Joined         bool
+	Reason         string // why the server was lost
+}
+
+type WorkerChangeResponse struct{}
+
 type RunGameRequest struct {
 	Turns   int
 	Height  int
 	Width   int
 	Threads int
 	World   [][]byte
+	Packed  PackedWorld // used instead of World once an encoding has been agreed
+
+	Compression string // world encoding agreed in ReadyToDial for the final world, "" for none
+
+	DetectPeriod int  // longest period of oscillation to look for, 0 to not look
+	SkipCycles   bool // whether to jump to the last turn once the world is found to repeat
+
+	RegionColumns int // size of the grid of tiles to keep population statistics for, 0 to not keep them
+	RegionRows    int
 }
 
 type RunGameResponse struct {
 	World          [][]byte
+	Packed         PackedWorld
 	AliveCells     []util.Cell
 	CompletedTurns int
 }
@@ -53,10 +98,14 @@ type AliveCellsCountResponse struct {
 	CellsCount     int
 }
 
-type ScreenshotRequest struct{}
+type ScreenshotRequest struct {
+	Compression string // world encoding agreed in ReadyToDial, "" for none
+}
 
 type ScreenshotResponse struct {
-	World [][]byte
+	World          [][]byte
+	Packed         PackedWorld
+	CompletedTurns int
 }
 
 type QuitRequest struct{}
@@ -79,6 +128,36 @@ type RestartResponse struct {
 	Turn int
 }
 
+type StepRequest struct{}
+
+type StepResponse struct {
+	Turn int
+}
+
+type CensusRequest struct{}
+
+type CensusResponse struct {
+	CompletedTurns int
+	Census         util.Census
+}
+
+type RegionStatsRequest struct{}
+
+type RegionStatsResponse struct {
+	CompletedTurns int
+	Stats          util.RegionStats
+}
+
+// EditCellsRequest lists the cells of a paused game to toggle, which may repeat.
+type EditCellsRequest struct {
+	Cells []util.Cell
+}
+
+type EditCellsResponse struct {
+	CompletedTurns int
+	CellsFlipped   []util.Cell // the cells toggled an odd number of times, which have changed state
+}
+
 type NextStateRequest struct {
 	StartY      int
 	EndY        int
@@ -91,7 +170,8 @@ type NextStateRequest struct {
 }
 
 type NextStateResponse struct {
-	World [][]byte
+	World   [][]byte
+	Elapsed time.Duration // time the server spent computing the slice
 }
 
 type CloseServerRequest struct{}
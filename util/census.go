@@ -0,0 +1,319 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Census counts the objects in a world by name, such as "block" or "glider".
+// Objects that are not recognised are counted by their number of cells, as "other (N cells)" or "other (1 cell)".
+type Census map[string]int
+
+// CensusEntry is the count of one kind of object in a Census.
+type CensusEntry struct {
+	Name  string
+	Count int
+}
+
+// Entries returns the kinds of object counted, most common first, then by name.
+func (c Census) Entries() []CensusEntry {
+	entries := make([]CensusEntry, 0, len(c))
+	for name, count := range c {
+		entries = append(entries, CensusEntry{name, count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Total returns the number of objects counted.
+func (c Census) Total() int {
+	total := 0
+	for _, count := range c {
+		total += count
+	}
+	return total
+}
+
+// String summarises the census on one line, counting all of the objects that were not recognised together.
+func (c Census) String() string {
+	if len(c) == 0 {
+		return "no objects"
+	}
+	var parts []string
+	others := 0
+	for _, e := range c.Entries() {
+		if strings.HasPrefix(e.Name, otherObject) {
+			others += e.Count
+		} else {
+			parts = append(parts, fmt.Sprintf("%v %v", e.Count, e.Name))
+		}
+	}
+	if others > 0 {
+		parts = append(parts, fmt.Sprintf("%v %v", others, otherObject))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// otherObject starts the name of each object that is not recognised.
+const otherObject = "other"
+
+func otherName(cells int) string {
+	if cells == 1 {
+		return otherObject + " (1 cell)"
+	}
+	return fmt.Sprintf("%v (%v cells)", otherObject, cells)
+}
+
+// knownObject is an object that a census recognises in any of its phases and orientations.
+type knownObject struct {
+	name    string
+	period  int
+	picture string // one phase, with rows separated by / and O for an alive cell
+}
+
+var knownObjects = []knownObject{
+	{"block", 1, "OO/OO"},
+	{"beehive", 1, ".OO./O..O/.OO."},
+	{"loaf", 1, ".OO./O..O/.O.O/..O."},
+	{"boat", 1, "OO./O.O/.O."},
+	{"ship", 1, "OO./O.O/.OO"},
+	{"tub", 1, ".O./O.O/.O."},
+	{"pond", 1, ".OO./O..O/O..O/.OO."},
+	{"long boat", 1, ".O../O.O./.O.O/..OO"},
+	{"barge", 1, ".O../O.O./.O.O/..O."},
+	{"eater 1", 1, "OO../O.O./..O./..OO"},
+	{"blinker", 2, "OOO"},
+	{"toad", 2, ".OOO/OOO."},
+	{"beacon", 2, "OO../OO../..OO/..OO"},
+	{"pulsar", 3, "..OOO...OOO../............./O....O.O....O/O....O.O....O/O....O.O....O/..OOO...OOO../" +
+		"............./..OOO...OOO../O....O.O....O/O....O.O....O/O....O.O....O/............./..OOO...OOO.."},
+	{"pentadecathlon", 15, "..O....O../OO.OOOO.OO/..O....O.."},
+	{"glider", 4, ".O./..O/OOO"},
+	{"LWSS", 4, ".O..O/O..../O...O/OOOO."},
+	{"MWSS", 4, "...O../.O...O/O...../O....O/OOOOO."},
+	{"HWSS", 4, "...OO../.O....O/O....../O.....O/OOOOOO."},
+}
+
+var (
+	knownShapesOnce sync.Once
+	knownShapes     map[string]string // shapeKey of every phase and orientation of each known object, to its name
+	maxKnownCells   int               // most cells in any phase of a known object
+)
+
+// loadKnownShapes fills in knownShapes, by running each known object through its period in each orientation.
+func loadKnownShapes() {
+	knownShapes = make(map[string]string)
+	for _, object := range knownObjects {
+		cells := parsePicture(object.picture)
+		for phase := 0; phase < object.period; phase++ {
+			if len(cells) > maxKnownCells {
+				maxKnownCells = len(cells)
+			}
+			for orientation := 0; orientation < 8; orientation++ {
+				knownShapes[shapeKey(orient(cells, orientation))] = object.name
+			}
+			cells = evolve(cells)
+		}
+	}
+}
+
+func parsePicture(picture string) []Cell {
+	var cells []Cell
+	for y, row := range strings.Split(picture, "/") {
+		for x, c := range row {
+			if c == 'O' {
+				cells = append(cells, Cell{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// orient returns cells reflected and rotated into the orientation given by 0-7.
+func orient(cells []Cell, orientation int) []Cell {
+	oriented := make([]Cell, len(cells))
+	for i, c := range cells {
+		if orientation&1 != 0 {
+			c.X = -c.X
+		}
+		if orientation&2 != 0 {
+			c.Y = -c.Y
+		}
+		if orientation&4 != 0 {
+			c.X, c.Y = c.Y, c.X
+		}
+		oriented[i] = c
+	}
+	return oriented
+}
+
+// shapeKey identifies the shape made by cells wherever they are, by listing them relative to their top left corner.
+func shapeKey(cells []Cell) string {
+	minX, minY := cells[0].X, cells[0].Y
+	for _, c := range cells {
+		if c.X < minX {
+			minX = c.X
+		}
+		if c.Y < minY {
+			minY = c.Y
+		}
+	}
+	shape := make([]Cell, len(cells))
+	for i, c := range cells {
+		shape[i] = Cell{c.X - minX, c.Y - minY}
+	}
+	sort.Slice(shape, func(i, j int) bool {
+		if shape[i].Y != shape[j].Y {
+			return shape[i].Y < shape[j].Y
+		}
+		return shape[i].X < shape[j].X
+	})
+	var key strings.Builder
+	for _, c := range shape {
+		fmt.Fprintf(&key, "%v,%v;", c.X, c.Y)
+	}
+	return key.String()
+}
+
+// evolve returns the next generation of cells in an unbounded world.
+func evolve(cells []Cell) []Cell {
+	alive := make(map[Cell]bool, len(cells))
+	neighbours := make(map[Cell]int)
+	for _, c := range cells {
+		alive[c] = true
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx != 0 || dy != 0 {
+					neighbours[Cell{c.X + dx, c.Y + dy}]++
+				}
+			}
+		}
+	}
+	var next []Cell
+	for c, n := range neighbours {
+		if n == 3 || (n == 2 && alive[c]) {
+			next = append(next, c)
+		}
+	}
+	return next
+}
+
+// recognise returns the name of the known object that cells make, if they make one.
+func recognise(cells []Cell) (string, bool) {
+	knownShapesOnce.Do(loadKnownShapes)
+	if len(cells) == 0 || len(cells) > maxKnownCells {
+		return "", false
+	}
+	name, ok := knownShapes[shapeKey(cells)]
+	return name, ok
+}
+
+// censusCell records which component an alive cell belongs to, and where it is relative to the rest of the
+// component, since a component that crosses the edge of the world carries on at the other side.
+type censusCell struct {
+	component int
+	at        Cell
+}
+
+// TakeCensus counts the objects in world, where 255 is alive, which wraps around at its edges.
+// Each object is a component of alive cells connected to each other by their 8 neighbours, or for objects such as
+// the pulsar whose phases are not all connected, a group of components within 2 cells of each other.
+func TakeCensus(world [][]byte) Census {
+	census := make(Census)
+	height := len(world)
+	if height == 0 {
+		return census
+	}
+	width := len(world[0])
+	index := func(c Cell) int {
+		return wrap(c.Y, height)*width + wrap(c.X, width)
+	}
+
+	// find the components, placing each cell by the step taken to it from its neighbour
+	cells := make(map[int]censusCell)
+	var components [][]Cell
+	for y, row := range world {
+		for x, b := range row {
+			if b != 255 {
+				continue
+			}
+			if _, seen := cells[y*width+x]; seen {
+				continue
+			}
+			n := len(components)
+			component := []Cell{{x, y}}
+			cells[y*width+x] = censusCell{n, Cell{x, y}}
+			for i := 0; i < len(component); i++ {
+				c := component[i]
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						next := Cell{c.X + dx, c.Y + dy}
+						if world[wrap(next.Y, height)][wrap(next.X, width)] != 255 {
+							continue
+						}
+						if _, seen := cells[index(next)]; !seen {
+							cells[index(next)] = censusCell{n, next}
+							component = append(component, next)
+						}
+					}
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	// group components within 2 cells of each other, moving each one next to the cell it was found near,
+	// in case it was found across the edge of the world
+	grouped := make([]bool, len(components))
+	for i := range components {
+		if grouped[i] {
+			continue
+		}
+		grouped[i] = true
+		members := []int{i}
+		group := append([]Cell(nil), components[i]...)
+		for k := 0; k < len(group); k++ {
+			c := group[k]
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					near := Cell{c.X + dx, c.Y + dy}
+					other, ok := cells[index(near)]
+					if !ok || grouped[other.component] {
+						continue
+					}
+					grouped[other.component] = true
+					members = append(members, other.component)
+					for _, o := range components[other.component] {
+						group = append(group, Cell{o.X + near.X - other.at.X, o.Y + near.Y - other.at.Y})
+					}
+				}
+			}
+		}
+
+		// a group is either one object, or objects that happen to be close together
+		if name, ok := recognise(group); ok {
+			census[name]++
+			continue
+		}
+		for _, m := range members {
+			if name, ok := recognise(components[m]); ok {
+				census[name]++
+			} else {
+				census[otherName(len(components[m]))]++
+			}
+		}
+	}
+	return census
+}
+
+// wrap returns i modulo n, for i that may be negative.
+func wrap(i, n int) int {
+	return ((i % n) + n) % n
+}
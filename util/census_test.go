@@ -0,0 +1,61 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestKnownObjects checks that each known object returns to its own shape after its period, and not before,
+// so that every phase of it is recognised.
+func TestKnownObjects(t *testing.T) {
+	for _, object := range knownObjects {
+		cells := parsePicture(object.picture)
+		key := shapeKey(cells)
+		for turn := 1; turn <= object.period; turn++ {
+			cells = evolve(cells)
+			if len(cells) == 0 {
+				t.Errorf("%v dies after %v turns", object.name, turn)
+				break
+			}
+			if repeated := shapeKey(cells) == key; repeated != (turn == object.period) {
+				t.Errorf("%v does not have period %v, it repeats after %v turns", object.name, object.period, turn)
+				break
+			}
+		}
+	}
+}
+
+// TestTakeCensus checks that objects are counted in any phase and orientation, including across the edge of
+// the world, and that objects close together are still told apart.
+func TestTakeCensus(t *testing.T) {
+	world := make([][]byte, 64)
+	for y := range world {
+		world[y] = make([]byte, 64)
+	}
+	place := func(picture string, x, y, orientation int) {
+		for _, c := range orient(parsePicture(picture), orientation) {
+			world[wrap(y+c.Y, 64)][wrap(x+c.X, 64)] = 255
+		}
+	}
+	place("OO/OO", 2, 2, 0)
+	place("OO/OO", 6, 2, 0) // two cells from the first block
+	place("OOO", 10, 10, 4) // vertical blinker
+	place(".O./..O/OOO", 62, 62, 3)
+	place(".OO./O..O/.OO.", 30, 5, 4)
+	place("..OOO...OOO../............./O....O.O....O/O....O.O....O/O....O.O....O/..OOO...OOO../"+
+		"............./..OOO...OOO../O....O.O....O/O....O.O....O/O....O.O....O/............./..OOO...OOO..", 20, 20, 0)
+	place("OOOOO", 50, 10, 0) // not a known object
+	place("O", 50, 40, 0)
+
+	expected := Census{"block": 2, "blinker": 1, "glider": 1, "beehive": 1, "pulsar": 1, "other (5 cells)": 1, "other (1 cell)": 1}
+	census := TakeCensus(world)
+	if !reflect.DeepEqual(census, expected) {
+		t.Errorf("expected %v, got %v", expected, census)
+	}
+	if census.Total() != 8 {
+		t.Errorf("expected 8 objects, got %v", census.Total())
+	}
+	if s := census.String(); s != "2 block, 1 beehive, 1 blinker, 1 glider, 1 pulsar, 2 other" {
+		t.Errorf("unexpected summary %q", s)
+	}
+}
@@ -0,0 +1,202 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxNetpbmCells guards against allocating a world for a corrupt header.
+const maxNetpbmCells = 1 << 28
+
+// ReadNetpbm reads a PBM (P1, P4) or PGM (P2, P5) image with any maxval into a world of alive (255) and dead (0) cells.
+// A PGM pixel is alive if it is more than half as bright as maxval. A PBM pixel is alive if it is black (1),
+// since that is how patterns are drawn.
+func ReadNetpbm(r io.Reader) ([][]byte, error) {
+	p := netpbmReader{r: bufio.NewReader(r)}
+	magic, err := p.token()
+	if err != nil {
+		return nil, headerError(err)
+	}
+	switch magic {
+	case "P1", "P2", "P4", "P5":
+	default:
+		return nil, fmt.Errorf("netpbm: %q is not a supported format, expected P1, P2, P4 or P5", magic)
+	}
+	width, err := p.int("width")
+	if err != nil {
+		return nil, headerError(err)
+	}
+	height, err := p.int("height")
+	if err != nil {
+		return nil, headerError(err)
+	}
+	if width <= 0 || height <= 0 || width*height > maxNetpbmCells {
+		return nil, fmt.Errorf("netpbm: invalid size %vx%v", width, height)
+	}
+	maxval := 1
+	if magic == "P2" || magic == "P5" {
+		if maxval, err = p.int("maxval"); err != nil {
+			return nil, headerError(err)
+		}
+		if maxval <= 0 || maxval > 65535 {
+			return nil, fmt.Errorf("netpbm: invalid maxval %v", maxval)
+		}
+	}
+	if magic == "P4" || magic == "P5" {
+		// the header of a binary image ends with a single whitespace byte
+		b, err := p.r.ReadByte()
+		if err != nil || !isSpace(b) {
+			return nil, errors.New("netpbm: expected whitespace before the image data")
+		}
+	}
+
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+		if err := p.readRow(magic, world[y], maxval); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("netpbm: image data ends at row %v of %v", y, height)
+			}
+			return nil, err
+		}
+	}
+	return world, nil
+}
+
+func headerError(err error) error {
+	if err == io.EOF {
+		return errors.New("netpbm: header is incomplete")
+	}
+	return err
+}
+
+// netpbmReader splits the header and ascii image data into tokens, skipping comments.
+type netpbmReader struct {
+	r *bufio.Reader
+}
+
+// readRow reads a single row of the image into row.
+func (p *netpbmReader) readRow(magic string, row []byte, maxval int) error {
+	switch magic {
+	case "P1":
+		for x := range row {
+			b, err := p.skip()
+			if err != nil {
+				return err
+			}
+			switch b {
+			case '0':
+			case '1':
+				row[x] = 255
+			default:
+				return fmt.Errorf("netpbm: unexpected %q in image data", b)
+			}
+		}
+	case "P2":
+		for x := range row {
+			v, err := p.int("pixel")
+			if err != nil {
+				return err
+			}
+			if v > maxval {
+				return fmt.Errorf("netpbm: pixel %v is greater than maxval %v", v, maxval)
+			}
+			row[x] = alive(v, maxval)
+		}
+	case "P4":
+		packed := make([]byte, (len(row)+7)/8)
+		if _, err := io.ReadFull(p.r, packed); err != nil {
+			return err
+		}
+		for x := range row {
+			if packed[x/8]&(0x80>>uint(x%8)) != 0 {
+				row[x] = 255
+			}
+		}
+	case "P5":
+		size := 1
+		if maxval > 255 {
+			size = 2
+		}
+		data := make([]byte, len(row)*size)
+		if _, err := io.ReadFull(p.r, data); err != nil {
+			return err
+		}
+		for x := range row {
+			v := int(data[x*size])
+			if size == 2 {
+				v = v<<8 | int(data[x*size+1])
+			}
+			row[x] = alive(v, maxval)
+		}
+	}
+	return nil
+}
+
+// skip returns the next byte that is neither whitespace nor part of a comment.
+func (p *netpbmReader) skip() (byte, error) {
+	for {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case b == '#':
+			if _, err := p.r.ReadString('\n'); err != nil {
+				return 0, err
+			}
+		case !isSpace(b):
+			return b, nil
+		}
+	}
+}
+
+// token returns the next whitespace separated token, which may be ended by a comment.
+func (p *netpbmReader) token() (string, error) {
+	b, err := p.skip()
+	if err != nil {
+		return "", err
+	}
+	token := []byte{b}
+	for {
+		b, err := p.r.ReadByte()
+		if err == io.EOF {
+			return string(token), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if isSpace(b) || b == '#' {
+			// leave the terminator for the next token, as binary image data starts after exactly one whitespace byte
+			return string(token), p.r.UnreadByte()
+		}
+		token = append(token, b)
+	}
+}
+
+// int returns the next token as a number.
+func (p *netpbmReader) int(name string) (int, error) {
+	token, err := p.token()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("netpbm: invalid %v %q", name, token)
+	}
+	return n, nil
+}
+
+func alive(v, maxval int) byte {
+	if 2*v > maxval {
+		return 255
+	}
+	return 0
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestReadNetpbm checks that each format reads into the same world.
+func TestReadNetpbm(t *testing.T) {
+	expected := [][]byte{
+		{0, 255, 0},
+		{255, 0, 255},
+	}
+	for name, image := range map[string]string{
+		"P1":              "P1\n# a comment\n3 2\n0 1 0\n1 0 1\n",
+		"P1 packed":       "P1 3 2 010101",
+		"P2":              "P2\n3 2\n# comment between the header fields\n15\n0 15 7\n8 0 15\n",
+		"P4":              "P4\n3 2\n\x40\xa0",
+		"P5":              "P5 3#comment ending a token\n2 255\n\x00\xff\x7f\x80\x20\xc8",
+		"P5 whitespace":   "P5\n3 2\n255\n\x0a\xff\x20\x80\x0d\xc8",
+		"P5 16-bit":       "P5\n3 2\n1000\n\x00\x00\x03\xe8\x01\xf4\x01\xf5\x00\x00\x03\xe8",
+		"comment in data": "P2 3 2 1\n0 1 0 # the first row\n1 0 1\n",
+		"exactly half":    "P2 3 2 2\n0 2 1\n2 0 2\n",
+	} {
+		world, err := ReadNetpbm(strings.NewReader(image))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if len(world) != len(expected) {
+			t.Errorf("%v: expected %v rows, got %v", name, len(expected), len(world))
+			continue
+		}
+		for y := range expected {
+			if !bytes.Equal(world[y], expected[y]) {
+				t.Errorf("%v: row %v is %v, expected %v", name, y, world[y], expected[y])
+			}
+		}
+	}
+}
+
+// TestReadNetpbmErrors checks that bad images are reported rather than misread.
+func TestReadNetpbmErrors(t *testing.T) {
+	for name, image := range map[string]string{
+		"empty":           "",
+		"not netpbm":      "\x89PNG\r\n",
+		"ppm":             "P6\n3 2\n255\n",
+		"no maxval":       "P5\n3 2\n",
+		"bad width":       "P5\nthree 2\n255\n",
+		"zero size":       "P2\n0 2\n255\n",
+		"huge":            "P5\n100000 100000\n255\n",
+		"maxval too big":  "P2\n1 1\n70000\n0\n",
+		"pixel too big":   "P2\n1 1\n15\n16\n",
+		"bad P1 pixel":    "P1\n2 1\n0 2\n",
+		"truncated":       "P5\n3 2\n255\n\x00\xff\x00",
+		"truncated ascii": "P2\n3 2\n255\n0 0 0\n0\n",
+	} {
+		if _, err := ReadNetpbm(strings.NewReader(image)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package util
+
+// Region holds the population statistics of one tile of a world.
+type Region struct {
+	Alive    int // cells alive in the tile
+	Births   int // cells born in the tile in the latest turn
+	Deaths   int // cells that died in the tile in the latest turn
+	Activity int // births and deaths in the tile over all of the turns so far, for heatmaps
+}
+
+// RegionStats divides a world into a grid of tiles and keeps the population statistics of each.
+// Tiles are as close to the same size as the world allows.
+type RegionStats struct {
+	Width, Height int
+	Columns, Rows int
+	Regions       [][]Region // indexed [row][column]
+}
+
+// NewRegionStats returns statistics for world divided into columns x rows tiles, with its alive cells counted.
+func NewRegionStats(world [][]byte, columns, rows int) *RegionStats {
+	s := &RegionStats{Height: len(world), Columns: columns, Rows: rows}
+	if s.Height > 0 {
+		s.Width = len(world[0])
+	}
+	s.Regions = make([][]Region, rows)
+	for r := range s.Regions {
+		s.Regions[r] = make([]Region, columns)
+	}
+	for y, row := range world {
+		for x, b := range row {
+			if b == 255 {
+				s.region(Cell{x, y}).Alive++
+			}
+		}
+	}
+	return s
+}
+
+// Tile returns the row and column of the tile that holds c.
+func (s *RegionStats) Tile(c Cell) (row, column int) {
+	return c.Y * s.Rows / s.Height, c.X * s.Columns / s.Width
+}
+
+func (s *RegionStats) region(c Cell) *Region {
+	row, column := s.Tile(c)
+	return &s.Regions[row][column]
+}
+
+// Turn updates the statistics with the cells flipped by a turn, given the world after it.
+func (s *RegionStats) Turn(world [][]byte, flipped []Cell) {
+	for r := range s.Regions {
+		for c := range s.Regions[r] {
+			s.Regions[r][c].Births = 0
+			s.Regions[r][c].Deaths = 0
+		}
+	}
+	for _, c := range flipped {
+		region := s.region(c)
+		if world[c.Y][c.X] == 255 {
+			region.Alive++
+			region.Births++
+		} else {
+			region.Alive--
+			region.Deaths++
+		}
+		region.Activity++
+	}
+}
+
+// Copy returns a copy of the statistics that does not change with them.
+func (s *RegionStats) Copy() RegionStats {
+	copied := *s
+	copied.Regions = make([][]Region, len(s.Regions))
+	for r := range s.Regions {
+		copied.Regions[r] = append([]Region(nil), s.Regions[r]...)
+	}
+	return copied
+}
+
+// Busiest returns the row and column of the tile with the most activity, and of the tile with the most alive cells.
+func (s RegionStats) Busiest() (activeRow, activeColumn, aliveRow, aliveColumn int) {
+	for r, row := range s.Regions {
+		for c, region := range row {
+			if region.Activity > s.Regions[activeRow][activeColumn].Activity {
+				activeRow, activeColumn = r, c
+			}
+			if region.Alive > s.Regions[aliveRow][aliveColumn].Alive {
+				aliveRow, aliveColumn = r, c
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRegionStats checks that alive cells, births and deaths are counted in the tile they happen in,
+// with tiles of uneven sizes when the world does not divide evenly.
+func TestRegionStats(t *testing.T) {
+	world := make([][]byte, 5)
+	for y := range world {
+		world[y] = make([]byte, 6)
+	}
+	world[0][0] = 255
+	world[4][5] = 255
+	world[1][3] = 255
+
+	s := NewRegionStats(world, 2, 2)
+	if row, column := s.Tile(Cell{X: 3, Y: 2}); row != 0 || column != 1 {
+		t.Errorf("expected (3, 2) to be in tile 0, 1, got %v, %v", row, column)
+	}
+	expected := [][]Region{{{Alive: 1}, {Alive: 1}}, {{}, {Alive: 1}}}
+	if !reflect.DeepEqual(s.Regions, expected) {
+		t.Fatalf("expected %v, got %v", expected, s.Regions)
+	}
+
+	// (0, 0) dies, (1, 4) is born and (3, 1) dies then is born again
+	world[0][0] = 0
+	world[4][1] = 255
+	world[1][3] = 0
+	s.Turn(world, []Cell{{X: 0, Y: 0}, {X: 1, Y: 4}, {X: 3, Y: 1}})
+	snapshot := s.Copy()
+	world[1][3] = 255
+	s.Turn(world, []Cell{{X: 3, Y: 1}})
+
+	expected = [][]Region{
+		{{Alive: 0, Deaths: 1, Activity: 1}, {Alive: 0, Deaths: 1, Activity: 1}},
+		{{Alive: 1, Births: 1, Activity: 1}, {Alive: 1}},
+	}
+	if !reflect.DeepEqual(snapshot.Regions, expected) {
+		t.Errorf("after the first turn expected %v, got %v", expected, snapshot.Regions)
+	}
+	expected = [][]Region{
+		{{Activity: 1}, {Alive: 1, Births: 1, Activity: 2}},
+		{{Alive: 1, Activity: 1}, {Alive: 1}},
+	}
+	if !reflect.DeepEqual(s.Regions, expected) {
+		t.Errorf("after the second turn expected %v, got %v", expected, s.Regions)
+	}
+
+	activeRow, activeColumn, aliveRow, aliveColumn := s.Busiest()
+	if activeRow != 0 || activeColumn != 1 || aliveRow != 0 || aliveColumn != 1 {
+		t.Errorf("expected tile 0, 1 to be the busiest, got %v, %v and %v, %v", activeRow, activeColumn, aliveRow, aliveColumn)
+	}
+}